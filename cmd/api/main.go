@@ -2,67 +2,136 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"sync"
 	"syscall"
 	"time"
 
 	"monitoring-dashboard-automation/internal/config"
+	"monitoring-dashboard-automation/internal/health"
+	"monitoring-dashboard-automation/internal/logging"
 	httphandler "monitoring-dashboard-automation/internal/http"
 	"monitoring-dashboard-automation/internal/metrics"
+	"monitoring-dashboard-automation/internal/tracing"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Set at build time with -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "dev"
+	commit  = "unknown"
 )
 
 func main() {
+	startedAt := time.Now()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize logger
-	logger, err := initLogger(cfg.LogLevel)
+	// Initialize logger, with levels adjustable per component at runtime
+	logLevels := logging.NewLevels(logLevel(cfg.LogLevel))
+	logger, err := initLogger(cfg.LogLevel, logLevels, cfg.LogSampleInitial, cfg.LogSampleThereafter)
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer logger.Sync()
 
+	for _, warning := range cfg.Warnings {
+		logger.Warn("Configuration warning", zap.String("warning", warning))
+	}
+
 	// Initialize metrics
-	metricsRegistry := metrics.NewRegistry()
+	metricsRegistry := metrics.NewRegistry(metrics.WithHTTPDurationBuckets(cfg.HTTPDurationBuckets))
+
+	// Expose what the binary was built from
+	var modulePath string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		modulePath = info.Main.Path
+	}
+	metricsRegistry.SetBuildInfo(runtime.Version(), modulePath, version, commit)
+
+	// Initialize health checker
+	healthChecker := health.NewChecker()
+	healthChecker.AddCheck("metrics_registry", health.GatherCheck(metricsRegistry.GetRegistry()))
+	if cfg.PrometheusURL != "" {
+		healthChecker.AddCheck("prometheus", health.HTTPCheck("prometheus", cfg.PrometheusURL+"/-/ready", 2*time.Second))
+	}
+	if cfg.DataDir != "" {
+		healthChecker.AddCheck("data_dir", health.WritableDirCheck(cfg.DataDir))
+	}
+	if cfg.EnableCommandCheck && len(cfg.ReadinessCommand) > 0 {
+		healthChecker.AddCheck("command", health.CommandCheck(cfg.ReadinessCommand[0], cfg.ReadinessCommand[1:], cfg.ReadinessCommandTimeout))
+	}
+	healthChecker.SetCacheTTL(cfg.ReadinessCacheTTL)
+
+	// Initialize request tracing when a collector is configured
+	var tracerProvider *sdktrace.TracerProvider
+	var tracer trace.Tracer
+	if cfg.OtelExporterOTLPEndpoint != "" {
+		tracerProvider, err = tracing.NewProvider(context.Background(), cfg.OtelExporterOTLPEndpoint, serviceName)
+		if err != nil {
+			logger.Fatal("Failed to initialize tracing", zap.Error(err))
+		}
+		tracer = tracerProvider.Tracer(serviceName)
+		logger.Info("Tracing enabled", zap.String("endpoint", cfg.OtelExporterOTLPEndpoint))
+	}
+
+	// Fault injection toggles, kept here so shutdown can switch them off
+	injection := httphandler.NewToggles()
 
 	// Initialize HTTP router
-	router := httphandler.NewRouter(cfg, logger, metricsRegistry)
+	router := httphandler.NewRouter(cfg, logger, logLevels, metricsRegistry, healthChecker, tracer, injection)
 
 	// Create HTTP server
 	server := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: router,
+		Addr:      ":" + cfg.Port,
+		Handler:   router,
+		TLSConfig: newTLSConfig(cfg),
 	}
 
+	// Registry, checks and routes are wired; let the startup probe pass
+	healthChecker.MarkStarted()
+
 	// Start server in a goroutine
 	go func() {
-		logger.Info("Starting server", zap.String("port", cfg.Port))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("Starting server", zap.String("port", cfg.Port), zap.Bool("tls", cfg.TLSCertFile != ""))
+		if err := listenAndServe(server, cfg); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Server failed to start", zap.Error(err))
 		}
 	}()
 
-	// Wait for interrupt signal to gracefully shutdown the server
+	// Wait for interrupt signal to gracefully shutdown the server.
+	// SIGHUP during the drain phase aborts the shutdown and resumes serving.
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	logger.Info("Shutting down server...")
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	// Create a deadline for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	shutdown := newShutdownCoordinator(server, metricsRegistry, healthChecker, logger, cfg.ShutdownPollInterval)
+	shutdown.DisableOnShutdown(injection.Error, injection.Latency)
+	if tracerProvider != nil {
+		// Export the spans of the last requests before exiting
+		shutdown.AddHook(shutdownHook{Name: "tracer", Run: tracerProvider.Shutdown})
+	}
+	if cfg.ShutdownWebhookURL != "" {
+		// Announce the shutdown once, when it can no longer be aborted
+		shutdown.OnStop(func(ctx context.Context, reason string) {
+			notifyShutdown(ctx, cfg.ShutdownWebhookURL, newShutdownNotification(instanceName(cfg), reason, startedAt), logger)
+		})
+	}
 
-	// Perform graceful shutdown
-	if err := gracefulShutdown(ctx, server, metricsRegistry, logger); err != nil {
+	if err := handleSignals(quit, shutdown, cfg.ShutdownTimeout, logger); err != nil {
 		logger.Error("Graceful shutdown failed", zap.Error(err))
 		os.Exit(1)
 	}
@@ -70,66 +139,306 @@ func main() {
 	logger.Info("Server exited gracefully")
 }
 
-// gracefulShutdown handles the graceful shutdown process
-func gracefulShutdown(ctx context.Context, server *http.Server, metricsRegistry *metrics.Registry, logger *zap.Logger) error {
-	// Start shutdown process
-	shutdownComplete := make(chan error, 1)
-	
-	go func() {
-		// Wait for in-flight work jobs to complete
-		logger.Info("Waiting for in-flight work jobs to complete...")
-		
-		// Check for in-flight jobs periodically
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ctx.Done():
-				// Timeout reached, force shutdown
-				shutdownComplete <- ctx.Err()
-				return
-			case <-ticker.C:
-				inflightJobs := metricsRegistry.GetInflightJobs()
-				if inflightJobs == 0 {
-					logger.Info("All work jobs completed")
-					break
+// handleSignals starts the shutdown on SIGINT or SIGTERM and aborts a drain
+// on SIGHUP. It returns the result of the first shutdown that was not aborted.
+func handleSignals(quit <-chan os.Signal, shutdown *shutdownCoordinator, timeout time.Duration, logger *zap.Logger) error {
+	shutdownResult := make(chan error, 1)
+
+	for {
+		select {
+		case sig := <-quit:
+			if sig == syscall.SIGHUP {
+				if shutdown.Abort() {
+					logger.Warn("Shutdown aborted, resuming service")
 				}
-				logger.Info("Waiting for work jobs to complete", zap.Float64("inflight_jobs", inflightJobs))
+				continue
 			}
-			
-			// Break out of the for loop when no inflight jobs
-			if metricsRegistry.GetInflightJobs() == 0 {
-				break
+
+			logger.Info("Shutting down server...", zap.String("signal", sig.String()))
+			go func() {
+				// Create a deadline for shutdown
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+
+				shutdownResult <- shutdown.Shutdown(ctx, sig.String())
+			}()
+		case err := <-shutdownResult:
+			if errors.Is(err, errShutdownAborted) {
+				continue
 			}
+			return err
 		}
-		
-		// Shutdown HTTP server
-		logger.Info("Shutting down HTTP server...")
-		if err := server.Shutdown(ctx); err != nil {
-			shutdownComplete <- err
-			return
+	}
+}
+
+// serviceName identifies this service in exported traces
+const serviceName = "go-app"
+
+// errShutdownAborted is returned by Shutdown when the drain was aborted
+var errShutdownAborted = errors.New("shutdown aborted")
+
+// shutdownRun tracks a single graceful shutdown attempt
+type shutdownRun struct {
+	done    chan struct{}
+	err     error
+	cancel  context.CancelFunc
+	aborted bool
+	reason  string
+}
+
+// shutdownCoordinator runs the graceful shutdown exactly once, even
+// when several triggers (signals, admin endpoints) fire at the same time.
+// While in-flight work is still draining the shutdown can be aborted.
+type shutdownCoordinator struct {
+	mu              sync.Mutex
+	current         *shutdownRun
+	stopping        bool
+	server          *http.Server
+	metricsRegistry *metrics.Registry
+	healthChecker   *health.Checker
+	logger          *zap.Logger
+	hooks           []shutdownHook
+	injection       []injectionToggle
+	onStop          func(ctx context.Context, reason string)
+
+	// How often the drain checks for remaining in-flight jobs
+	pollInterval time.Duration
+}
+
+// newShutdownCoordinator creates a shutdown coordinator for the given server
+func newShutdownCoordinator(server *http.Server, metricsRegistry *metrics.Registry, healthChecker *health.Checker, logger *zap.Logger, pollInterval time.Duration) *shutdownCoordinator {
+	return &shutdownCoordinator{
+		server:          server,
+		metricsRegistry: metricsRegistry,
+		healthChecker:   healthChecker,
+		logger:          logger,
+		pollInterval:    pollInterval,
+	}
+}
+
+// AddHook registers a hook run after the HTTP server has stopped
+func (s *shutdownCoordinator) AddHook(hook shutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// DisableOnShutdown registers fault injection toggles switched off as soon as
+// a shutdown starts
+func (s *shutdownCoordinator) DisableOnShutdown(toggles ...injectionToggle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injection = append(s.injection, toggles...)
+}
+
+// OnStop registers fn to be told once why the server is going down. It is
+// called after the drain, when the shutdown can no longer be aborted.
+func (s *shutdownCoordinator) OnStop(fn func(ctx context.Context, reason string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onStop = fn
+}
+
+// Shutdown runs the graceful shutdown for reason on the first call; concurrent
+// and subsequent calls block until it finishes and return the same result
+func (s *shutdownCoordinator) Shutdown(ctx context.Context, reason string) error {
+	s.mu.Lock()
+	if run := s.current; run != nil {
+		s.mu.Unlock()
+		<-run.done
+		return run.err
+	}
+	drainCtx, cancel := context.WithCancel(ctx)
+	run := &shutdownRun{done: make(chan struct{}), cancel: cancel, reason: reason}
+	s.current = run
+	injection := append([]injectionToggle(nil), s.injection...)
+	s.mu.Unlock()
+
+	defer close(run.done)
+	defer cancel()
+
+	// Stop failing and delaying the requests that are left; injection stays
+	// off even if the shutdown is aborted
+	disableInjection(injection, s.logger)
+
+	// Fail readiness so load balancers stop sending new traffic
+	s.healthChecker.SetDraining(true)
+
+	err := drainInflightJobs(drainCtx, s.metricsRegistry, s.logger, s.pollInterval)
+
+	s.mu.Lock()
+	if run.aborted {
+		// Allow a later trigger to start a fresh shutdown
+		s.current = nil
+		s.mu.Unlock()
+
+		s.healthChecker.SetDraining(false)
+		run.err = errShutdownAborted
+		return run.err
+	}
+	s.stopping = true
+	hooks := append([]shutdownHook(nil), s.hooks...)
+	onStop := s.onStop
+	s.mu.Unlock()
+
+	if onStop != nil {
+		onStop(ctx, run.reason)
+	}
+
+	if err == nil {
+		err = stopServer(ctx, s.server, s.metricsRegistry, s.logger)
+	}
+	// Hooks run even when the drain or stop failed, since that is when
+	// buffered spans would otherwise be lost
+	runShutdownHooks(hooks, s.logger)
+	run.err = err
+	return run.err
+}
+
+// Abort cancels an in-progress drain and restores readiness. It returns false
+// when no shutdown is running or the HTTP server is already shutting down.
+func (s *shutdownCoordinator) Abort() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || s.stopping || s.current.aborted {
+		return false
+	}
+
+	s.current.aborted = true
+	s.current.cancel()
+	return true
+}
+
+// injectionToggle is a fault injection toggle that shutdown switches off
+type injectionToggle interface {
+	Disable()
+}
+
+// disableInjection switches off fault injection so requests still in flight
+// during shutdown are not failed or delayed on purpose
+func disableInjection(toggles []injectionToggle, logger *zap.Logger) {
+	if len(toggles) == 0 {
+		return
+	}
+	for _, toggle := range toggles {
+		toggle.Disable()
+	}
+	logger.Info("Fault injection disabled for shutdown", zap.Int("toggles", len(toggles)))
+}
+
+// defaultShutdownHookTimeout bounds each shutdown hook when none is configured
+const defaultShutdownHookTimeout = 5 * time.Second
+
+// shutdownHook flushes or closes a component once the server has stopped,
+// such as a tracer provider that still buffers spans
+type shutdownHook struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// runShutdownHooks runs each hook in order with a fresh context bounded by its
+// own deadline, so a shutdown that already used up its timeout still flushes.
+// Failures are logged but do not stop the remaining hooks.
+func runShutdownHooks(hooks []shutdownHook, logger *zap.Logger) {
+	for _, hook := range hooks {
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = defaultShutdownHookTimeout
 		}
-		
-		// Flush metrics
-		logger.Info("Flushing metrics...")
-		if err := metricsRegistry.Flush(); err != nil {
-			logger.Warn("Failed to flush metrics", zap.Error(err))
+
+		logger.Info("Running shutdown hook", zap.String("hook", hook.Name))
+
+		hookCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := runWithContext(hookCtx, hook.Run)
+		cancel()
+
+		if err != nil {
+			logger.Warn("Shutdown hook failed", zap.String("hook", hook.Name), zap.Error(err))
 		}
-		
-		shutdownComplete <- nil
+	}
+}
+
+// runWithContext runs fn and returns early with ctx.Err() if ctx ends first,
+// so a hook that ignores its context cannot block shutdown
+func runWithContext(ctx context.Context, fn func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
 	}()
-	
-	// Wait for shutdown to complete or timeout
+
 	select {
-	case err := <-shutdownComplete:
+	case err := <-done:
 		return err
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
 
-func initLogger(level string) (*zap.Logger, error) {
+// drainInflightJobs waits for in-flight work jobs to complete or ctx to end
+func drainInflightJobs(ctx context.Context, metricsRegistry *metrics.Registry, logger *zap.Logger, pollInterval time.Duration) error {
+	// Wait for in-flight work jobs to complete
+	logger.Info("Waiting for in-flight work jobs to complete...")
+	
+	// Check for in-flight jobs periodically
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			// Timeout reached (or drain aborted)
+			return ctx.Err()
+		case <-ticker.C:
+			inflightJobs := metricsRegistry.GetInflightJobs()
+			if inflightJobs == 0 {
+				logger.Info("All work jobs completed")
+				return nil
+			}
+			logger.Info("Waiting for work jobs to complete", zap.Float64("inflight_jobs", inflightJobs))
+		}
+	}
+}
+
+// stopServer shuts down the HTTP server and flushes metrics
+func stopServer(ctx context.Context, server *http.Server, metricsRegistry *metrics.Registry, logger *zap.Logger) error {
+	// Shutdown HTTP server
+	logger.Info("Shutting down HTTP server...")
+	if err := server.Shutdown(ctx); err != nil {
+		return err
+	}
+	
+	// Flush metrics
+	logger.Info("Flushing metrics...")
+	if err := metricsRegistry.Flush(); err != nil {
+		logger.Warn("Failed to flush metrics", zap.Error(err))
+	}
+	
+	return nil
+}
+
+// instanceName identifies this instance in notifications as host:port
+func instanceName(cfg *config.Config) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return host + ":" + cfg.Port
+}
+
+// Log sampling used in production unless LOG_SAMPLE_INITIAL and
+// LOG_SAMPLE_THEREAFTER override it, matching zap's production defaults
+const (
+	defaultLogSampleInitial    = 100
+	defaultLogSampleThereafter = 100
+)
+
+// initLogger builds the logger for LOG_LEVEL. The encoder accepts every level
+// and levels decides what is logged, so components can be made more verbose
+// at runtime. Logs are sampled when sampleInitial is positive, and by default
+// in production.
+func initLogger(level string, levels *logging.Levels, sampleInitial, sampleThereafter int) (*zap.Logger, error) {
 	var config zap.Config
 	
 	switch level {
@@ -137,9 +446,44 @@ func initLogger(level string) (*zap.Logger, error) {
 		config = zap.NewDevelopmentConfig()
 	case "production":
 		config = zap.NewProductionConfig()
+		if sampleInitial <= 0 {
+			sampleInitial, sampleThereafter = defaultLogSampleInitial, defaultLogSampleThereafter
+		}
 	default:
 		config = zap.NewDevelopmentConfig()
 	}
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	// Sampling sits below the component levels so only entries that would be
+	// logged count towards the per-second budget
+	config.Sampling = nil
+	var opts []zap.Option
+	if sampleInitial > 0 {
+		opts = append(opts, zap.WrapCore(sampleLogs(sampleInitial, sampleThereafter)))
+	}
+	opts = append(opts, zap.WrapCore(levels.Wrap))
+
+	return config.Build(opts...)
+}
+
+// sampleLogs wraps a core so that, each second, the first initial entries with
+// a given level and message are logged and then every thereafter-th one
+func sampleLogs(initial, thereafter int) func(zapcore.Core) zapcore.Core {
+	return func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, time.Second, initial, thereafter)
+	}
+}
 
-	return config.Build()
+// logLevel returns the default component log level for LOG_LEVEL
+func logLevel(level string) zapcore.Level {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
 }
\ No newline at end of file
@@ -2,21 +2,29 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"monitoring-dashboard-automation/internal/config"
+	"monitoring-dashboard-automation/internal/health"
 	httphandler "monitoring-dashboard-automation/internal/http"
+	"monitoring-dashboard-automation/internal/logging"
 	"monitoring-dashboard-automation/internal/metrics"
 
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 )
 
-func TestGracefulShutdown(t *testing.T) {
+func TestShutdownCoordinator_Shutdown(t *testing.T) {
 	tests := []struct {
 		name           string
 		inflightJobs   int
@@ -59,7 +67,8 @@ func TestGracefulShutdown(t *testing.T) {
 			}
 			
 			// Create router and server
-			router := httphandler.NewRouter(cfg, logger, metricsRegistry)
+			healthChecker := health.NewChecker()
+			router := httphandler.NewRouter(cfg, logger, logging.NewLevels(zapcore.InfoLevel), metricsRegistry, healthChecker, nil, nil)
 			server := httptest.NewServer(router)
 			defer server.Close()
 			
@@ -89,7 +98,7 @@ func TestGracefulShutdown(t *testing.T) {
 			defer cancel()
 			
 			// Test graceful shutdown
-			err := gracefulShutdown(ctx, server.Config, metricsRegistry, logger)
+			err := newShutdownCoordinator(server.Config, metricsRegistry, healthChecker, logger, time.Second).Shutdown(ctx, "terminated")
 			
 			if tt.expectError && err == nil {
 				t.Error("Expected error but got none")
@@ -104,7 +113,7 @@ func TestGracefulShutdown(t *testing.T) {
 	}
 }
 
-func TestGracefulShutdownWithRealServer(t *testing.T) {
+func TestShutdownCoordinator_ShutdownWithRealServer(t *testing.T) {
 	// Create test logger
 	logger := zaptest.NewLogger(t)
 	
@@ -119,7 +128,8 @@ func TestGracefulShutdownWithRealServer(t *testing.T) {
 	}
 	
 	// Create router
-	router := httphandler.NewRouter(cfg, logger, metricsRegistry)
+	healthChecker := health.NewChecker()
+	router := httphandler.NewRouter(cfg, logger, logging.NewLevels(zapcore.InfoLevel), metricsRegistry, healthChecker, nil, nil)
 	
 	// Create HTTP server
 	server := &http.Server{
@@ -172,12 +182,394 @@ func TestGracefulShutdownWithRealServer(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	
-	err := gracefulShutdown(ctx, server, metricsRegistry, logger)
+	err := newShutdownCoordinator(server, metricsRegistry, healthChecker, logger, time.Second).Shutdown(ctx, "terminated")
 	if err != nil {
 		t.Errorf("Graceful shutdown failed: %v", err)
 	}
 }
 
+func TestShutdownCoordinator_DisablesInjection(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	
+	injection := httphandler.NewToggles()
+	injection.Error.SetConfig(true, 1.0, 503)
+	injection.Latency.SetConfig(true, 100, 200)
+	
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	shutdown := newShutdownCoordinator(server, metrics.NewRegistry(), health.NewChecker(), logger, 10*time.Millisecond)
+	shutdown.DisableOnShutdown(injection.Error, injection.Latency)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := shutdown.Shutdown(ctx, "terminated"); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if enabled, _, _ := injection.Error.GetConfig(); enabled {
+		t.Error("Expected error injection to be disabled after shutdown")
+	}
+	if enabled, _, _ := injection.Latency.GetConfig(); enabled {
+		t.Error("Expected latency injection to be disabled after shutdown")
+	}
+	if logs.FilterMessage("Fault injection disabled for shutdown").Len() != 1 {
+		t.Error("Expected disabling injection to be logged")
+	}
+}
+
+func TestShutdownCoordinator_ConcurrentTriggers(t *testing.T) {
+	// Capture logs so we can count how often the drain/flush steps ran
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	
+	metricsRegistry := metrics.NewRegistry()
+	cfg := &config.Config{
+		Port:       "0",
+		AdminToken: "test-token",
+		LogLevel:   "debug",
+	}
+	
+	healthChecker := health.NewChecker()
+	router := httphandler.NewRouter(cfg, logger, logging.NewLevels(zapcore.InfoLevel), metricsRegistry, healthChecker, nil, nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	
+	shutdown := newShutdownCoordinator(server.Config, metricsRegistry, healthChecker, logger, time.Second)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	// Fire two shutdown triggers at the same time (e.g. SIGTERM and admin endpoint)
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = shutdown.Shutdown(ctx, "terminated")
+		}(i)
+	}
+	wg.Wait()
+	
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Trigger %d: expected no error, got %v", i, err)
+		}
+	}
+	
+	if n := logs.FilterMessage("Waiting for in-flight work jobs to complete...").Len(); n != 1 {
+		t.Errorf("Expected drain to run once, ran %d times", n)
+	}
+	
+	if n := logs.FilterMessage("Flushing metrics...").Len(); n != 1 {
+		t.Errorf("Expected flush to run once, ran %d times", n)
+	}
+	
+	// A later trigger is a no-op returning the first result
+	if err := shutdown.Shutdown(ctx, "terminated"); err != nil {
+		t.Errorf("Expected repeated shutdown to return first result, got %v", err)
+	}
+	
+	if n := logs.FilterMessage("Flushing metrics...").Len(); n != 1 {
+		t.Errorf("Expected repeated shutdown to be a no-op, flush ran %d times", n)
+	}
+}
+
+func TestShutdownCoordinator_AbortDrain(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	metricsRegistry := metrics.NewRegistry()
+	healthChecker := health.NewChecker()
+	cfg := &config.Config{
+		Port:       "0",
+		AdminToken: "test-token",
+		LogLevel:   "debug",
+	}
+	
+	router := httphandler.NewRouter(cfg, logger, logging.NewLevels(zapcore.InfoLevel), metricsRegistry, healthChecker, nil, nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	
+	// Keep a job in flight so the shutdown stays in the drain phase
+	metricsRegistry.IncWorkJobsInflight()
+	defer metricsRegistry.DecWorkJobsInflight()
+	
+	shutdown := newShutdownCoordinator(server.Config, metricsRegistry, healthChecker, logger, time.Second)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	result := make(chan error, 1)
+	go func() {
+		result <- shutdown.Shutdown(ctx, "terminated")
+	}()
+	
+	// Readiness flips to 503 once draining starts
+	deadline := time.Now().Add(2 * time.Second)
+	for getStatus(t, server.URL+"/readyz") != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("Readiness did not fail while draining")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	
+	if !shutdown.Abort() {
+		t.Fatal("Expected Abort to cancel the in-progress drain")
+	}
+	
+	select {
+	case err := <-result:
+		if err != errShutdownAborted {
+			t.Errorf("Expected errShutdownAborted, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown did not return after abort")
+	}
+	
+	// Readiness is restored and the server keeps serving
+	if status := getStatus(t, server.URL+"/readyz"); status != http.StatusOK {
+		t.Errorf("Expected readiness 200 after abort, got %d", status)
+	}
+	
+	if status := getStatus(t, server.URL+"/api/v1/ping"); status != http.StatusOK {
+		t.Errorf("Expected server to keep serving after abort, got %d", status)
+	}
+	
+	// Nothing left to abort
+	if shutdown.Abort() {
+		t.Error("Expected Abort to be a no-op without a running shutdown")
+	}
+}
+
+// newNotifyingShutdown returns a shutdown coordinator that posts to a test
+// webhook on stop, and the channel the webhook receives notifications on
+func newNotifyingShutdown(t *testing.T, metricsRegistry *metrics.Registry, healthChecker *health.Checker) (*shutdownCoordinator, <-chan shutdownNotification) {
+	received := make(chan shutdownNotification, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification shutdownNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("Failed to decode notification: %v", err)
+		}
+		received <- notification
+	}))
+	t.Cleanup(webhook.Close)
+	
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	
+	logger := zaptest.NewLogger(t)
+	shutdown := newShutdownCoordinator(server.Config, metricsRegistry, healthChecker, logger, 10*time.Millisecond)
+	shutdown.OnStop(func(ctx context.Context, reason string) {
+		notifyShutdown(ctx, webhook.URL, newShutdownNotification("api-1", reason, time.Now()), logger)
+	})
+	return shutdown, received
+}
+
+func TestHandleSignals_NotifiesOnce(t *testing.T) {
+	shutdown, received := newNotifyingShutdown(t, metrics.NewRegistry(), health.NewChecker())
+	
+	// A second SIGTERM while the first shutdown runs must not notify again
+	quit := make(chan os.Signal, 2)
+	quit <- syscall.SIGTERM
+	quit <- syscall.SIGTERM
+	
+	if err := handleSignals(quit, shutdown, 5*time.Second, zaptest.NewLogger(t)); err != nil {
+		t.Fatalf("Expected graceful shutdown, got %v", err)
+	}
+	
+	if n := len(received); n != 1 {
+		t.Fatalf("Expected one shutdown notification, got %d", n)
+	}
+	if notification := <-received; notification.Reason != "terminated" {
+		t.Errorf("Expected reason terminated, got %q", notification.Reason)
+	}
+}
+
+func TestHandleSignals_AbortedShutdownDoesNotNotify(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	healthChecker := health.NewChecker()
+	shutdown, received := newNotifyingShutdown(t, metricsRegistry, healthChecker)
+	
+	// Keep a job in flight so the first shutdown stays in the drain phase
+	metricsRegistry.IncWorkJobsInflight()
+	
+	quit := make(chan os.Signal, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- handleSignals(quit, shutdown, 5*time.Second, zaptest.NewLogger(t))
+	}()
+	
+	waitForDraining := func(draining bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for healthChecker.IsDraining() != draining {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected draining to become %v", draining)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	
+	quit <- syscall.SIGTERM
+	waitForDraining(true)
+	quit <- syscall.SIGHUP
+	waitForDraining(false)
+	
+	if n := len(received); n != 0 {
+		t.Fatalf("Expected no notification for an aborted shutdown, got %d", n)
+	}
+	
+	metricsRegistry.DecWorkJobsInflight()
+	quit <- syscall.SIGINT
+	
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Expected graceful shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not finish")
+	}
+	
+	if n := len(received); n != 1 {
+		t.Fatalf("Expected one shutdown notification, got %d", n)
+	}
+	if notification := <-received; notification.Reason != "interrupt" {
+		t.Errorf("Expected reason interrupt, got %q", notification.Reason)
+	}
+}
+
+// memorySpanProvider mimics a tracer provider that batches spans in memory
+// and hands them to an in-memory exporter when shut down
+type memorySpanProvider struct {
+	mu       sync.Mutex
+	buffered []string
+	exported []string
+}
+
+func (p *memorySpanProvider) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exported = append(p.exported, p.buffered...)
+	p.buffered = nil
+	return nil
+}
+
+func TestShutdownCoordinator_FlushesTracerProvider(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	metricsRegistry := metrics.NewRegistry()
+	healthChecker := health.NewChecker()
+	cfg := &config.Config{
+		Port:       "0",
+		AdminToken: "test-token",
+		LogLevel:   "debug",
+	}
+	
+	router := httphandler.NewRouter(cfg, logger, logging.NewLevels(zapcore.InfoLevel), metricsRegistry, healthChecker, nil, nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	
+	provider := &memorySpanProvider{buffered: []string{"GET /api/v1/ping", "GET /api/v1/work"}}
+	
+	shutdown := newShutdownCoordinator(server.Config, metricsRegistry, healthChecker, logger, time.Second)
+	shutdown.AddHook(shutdownHook{Name: "tracer_provider", Run: provider.Shutdown})
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := shutdown.Shutdown(ctx, "terminated"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	
+	if len(provider.buffered) != 0 {
+		t.Errorf("Expected no buffered spans after shutdown, got %v", provider.buffered)
+	}
+	if len(provider.exported) != 2 {
+		t.Errorf("Expected 2 spans to be exported, got %v", provider.exported)
+	}
+}
+
+func TestShutdownCoordinator_FlushesTracerProviderAfterDrainTimeout(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	
+	// A job that never finishes makes the drain run out of time
+	metricsRegistry.IncWorkJobsInflight()
+	defer metricsRegistry.DecWorkJobsInflight()
+	
+	provider := &memorySpanProvider{buffered: []string{"GET /api/v1/work"}}
+	var hookErr error
+	
+	shutdown := newShutdownCoordinator(server.Config, metricsRegistry, health.NewChecker(), zaptest.NewLogger(t), 10*time.Millisecond)
+	shutdown.AddHook(shutdownHook{Name: "tracer_provider", Run: func(ctx context.Context) error {
+		hookErr = ctx.Err()
+		return provider.Shutdown(ctx)
+	}})
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	
+	if err := shutdown.Shutdown(ctx, "terminated"); err == nil {
+		t.Fatal("Expected the drain to time out")
+	}
+	
+	if hookErr != nil {
+		t.Errorf("Expected the hook to get a fresh context, got one that ended with %v", hookErr)
+	}
+	if len(provider.exported) != 1 {
+		t.Errorf("Expected the buffered span to be exported, got %v", provider.exported)
+	}
+}
+
+func TestRunShutdownHooks_Deadline(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	
+	ran := false
+	hooks := []shutdownHook{
+		{
+			// A hook that never returns must not block shutdown
+			Name:    "stuck",
+			Timeout: 50 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				select {}
+			},
+		},
+		{
+			Name: "next",
+			Run: func(ctx context.Context) error {
+				ran = true
+				return nil
+			},
+		},
+	}
+	
+	start := time.Now()
+	runShutdownHooks(hooks, logger)
+	
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected stuck hook to be cut off at its deadline, took %v", elapsed)
+	}
+	if !ran {
+		t.Error("Expected the remaining hooks to run after a failure")
+	}
+	if n := logs.FilterMessage("Shutdown hook failed").Len(); n != 1 {
+		t.Errorf("Expected 1 hook failure to be logged, got %d", n)
+	}
+}
+
+// getStatus performs a GET request and returns the response status code
+func getStatus(t *testing.T, url string) int {
+	t.Helper()
+	
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	
+	return resp.StatusCode
+}
+
 func TestMetricsFlush(t *testing.T) {
 	// Create metrics registry
 	metricsRegistry := metrics.NewRegistry()
@@ -220,7 +612,7 @@ func TestInitLogger(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			logger, err := initLogger(tt.level)
+			logger, err := initLogger(tt.level, logging.NewLevels(logLevel(tt.level)), 0, 0)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("initLogger() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -233,4 +625,28 @@ func TestInitLogger(t *testing.T) {
 			}
 		})
 	}
+}
+
+func TestSampleLogs(t *testing.T) {
+	const requests = 50
+	
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(sampleLogs(5, 1000)(core))
+	
+	cfg := &config.Config{AdminToken: "test-token", LogAccessFields: []string{"bytes"}}
+	router := httphandler.NewRouter(cfg, logger, logging.NewLevels(zapcore.InfoLevel), metrics.NewRegistry(), health.NewChecker(), nil, nil)
+	
+	for i := 0; i < requests; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	
+	// The first 5 per second are kept; a second boundary may let a few more in
+	completed := logs.FilterMessage("Request completed").Len()
+	if completed < 5 || completed >= requests {
+		t.Errorf("Expected sampling to keep at least 5 but fewer than %d access log lines, got %d", requests, completed)
+	}
 }
\ No newline at end of file
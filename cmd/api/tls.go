@@ -0,0 +1,24 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"monitoring-dashboard-automation/internal/config"
+)
+
+// newTLSConfig returns the server TLS settings, used when TLS_CERT_FILE and
+// TLS_KEY_FILE are set
+func newTLSConfig(cfg *config.Config) *tls.Config {
+	return &tls.Config{
+		MinVersion: cfg.TLSMinVersion(),
+	}
+}
+
+// listenAndServe serves HTTPS when a certificate is configured, HTTP otherwise
+func listenAndServe(server *http.Server, cfg *config.Config) error {
+	if cfg.TLSCertFile != "" {
+		return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return server.ListenAndServe()
+}
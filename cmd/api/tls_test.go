@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"monitoring-dashboard-automation/internal/config"
+)
+
+func TestNewTLSConfig_MinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = newTLSConfig(&config.Config{MinTLSVersion: "1.2"})
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(version uint16) error {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.MinVersion = version
+		transport.TLSClientConfig.MaxVersion = version
+		client := &http.Client{Transport: transport}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(tls.VersionTLS11); err == nil {
+		t.Error("Expected a TLS 1.1 client to be rejected")
+	}
+	if err := get(tls.VersionTLS12); err != nil {
+		t.Errorf("Expected a TLS 1.2 client to succeed, got %v", err)
+	}
+}
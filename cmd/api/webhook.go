@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// shutdownWebhookTimeout bounds the shutdown notification so an unreachable
+// webhook cannot hold up the shutdown
+const shutdownWebhookTimeout = 2 * time.Second
+
+// shutdownNotification is the payload POSTed to the shutdown webhook
+type shutdownNotification struct {
+	Event         string  `json:"event"`
+	Instance      string  `json:"instance"`
+	Reason        string  `json:"reason"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	Timestamp     string  `json:"timestamp"`
+}
+
+// newShutdownNotification describes instance going down for reason after running since startedAt
+func newShutdownNotification(instance, reason string, startedAt time.Time) shutdownNotification {
+	return shutdownNotification{
+		Event:         "shutdown",
+		Instance:      instance,
+		Reason:        reason,
+		UptimeSeconds: time.Since(startedAt).Seconds(),
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// notifyShutdown POSTs the notification to url, giving up after
+// shutdownWebhookTimeout. Failures are logged and otherwise ignored.
+func notifyShutdown(ctx context.Context, url string, notification shutdownNotification, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, shutdownWebhookTimeout)
+	defer cancel()
+
+	if err := postJSON(ctx, url, notification); err != nil {
+		logger.Warn("Shutdown webhook failed", zap.String("url", url), zap.Error(err))
+		return
+	}
+	logger.Info("Shutdown webhook notified", zap.String("url", url))
+}
+
+// postJSON POSTs body as JSON to url and fails on a status >= 400
+func postJSON(ctx context.Context, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNotifyShutdown(t *testing.T) {
+	received := make(chan shutdownNotification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		var notification shutdownNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("Failed to decode notification: %v", err)
+		}
+		received <- notification
+	}))
+	defer server.Close()
+	
+	startedAt := time.Now().Add(-time.Minute)
+	notifyShutdown(context.Background(), server.URL, newShutdownNotification("api-1", "terminated", startedAt), zap.NewNop())
+	
+	select {
+	case notification := <-received:
+		if notification.Instance != "api-1" || notification.Reason != "terminated" {
+			t.Errorf("Expected instance api-1 and reason terminated, got %+v", notification)
+		}
+		if notification.Event != "shutdown" {
+			t.Errorf("Expected event shutdown, got %s", notification.Event)
+		}
+		if notification.UptimeSeconds < 60 {
+			t.Errorf("Expected uptime of at least 60s, got %v", notification.UptimeSeconds)
+		}
+	default:
+		t.Fatal("Expected the webhook to receive a notification")
+	}
+}
+
+func TestNotifyShutdown_SlowWebhook(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	
+	core, logs := observer.New(zap.InfoLevel)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	
+	start := time.Now()
+	notifyShutdown(ctx, server.URL, newShutdownNotification("api-1", "terminated", time.Now()), zap.New(core))
+	
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected a hung webhook not to block shutdown, took %v", elapsed)
+	}
+	if logs.FilterMessage("Shutdown webhook failed").Len() != 1 {
+		t.Error("Expected the webhook failure to be logged")
+	}
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"monitoring-dashboard-automation/internal/httpjson"
+)
+
+// DefaultCapacity is the number of entries kept when no capacity is given
+const DefaultCapacity = 100
+
+// redactedValue replaces the value of any parameter that looks like a secret
+const redactedValue = "[REDACTED]"
+
+// Entry represents a single audited admin action
+type Entry struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Action    string                 `json:"action"`
+	TokenName string                 `json:"token_name"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+}
+
+// Log is a bounded, in-memory ring buffer of admin actions
+type Log struct {
+	mu      sync.RWMutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// NewLog creates an audit log holding at most capacity entries
+func NewLog(capacity int) *Log {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Log{
+		entries: make([]Entry, capacity),
+	}
+}
+
+// Record appends an entry, overwriting the oldest one when the log is full.
+// Parameters that look like secrets are redacted before being stored.
+func (l *Log) Record(action, tokenName string, params map[string]interface{}) {
+	entry := Entry{
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		TokenName: tokenName,
+		Params:    Redact(params),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Entries returns a copy of the recorded entries, oldest first
+func (l *Log) Entries() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if !l.full {
+		return append([]Entry(nil), l.entries[:l.next]...)
+	}
+
+	entries := make([]Entry, 0, len(l.entries))
+	entries = append(entries, l.entries[l.next:]...)
+	entries = append(entries, l.entries[:l.next]...)
+	return entries
+}
+
+// Redact returns a copy of params with secret-looking values replaced
+func Redact(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+
+	redacted := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		switch {
+		case isSecretKey(key):
+			redacted[key] = redactedValue
+		default:
+			if nested, ok := value.(map[string]interface{}); ok {
+				value = Redact(nested)
+			}
+			redacted[key] = value
+		}
+	}
+	return redacted
+}
+
+// isSecretKey reports whether a parameter name likely holds a secret
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range []string{"token", "secret", "password"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the audit log entries as JSON
+func Handler(log *Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"entries": log.Entries(),
+		}
+
+		httpjson.Write(w, r, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewLog(t *testing.T) {
+	log := NewLog(0)
+	if log == nil {
+		t.Fatal("NewLog() returned nil")
+	}
+
+	if len(log.entries) != DefaultCapacity {
+		t.Errorf("Expected default capacity %d, got %d", DefaultCapacity, len(log.entries))
+	}
+
+	if entries := log.Entries(); len(entries) != 0 {
+		t.Errorf("Expected empty log, got %d entries", len(entries))
+	}
+}
+
+func TestLog_Record(t *testing.T) {
+	log := NewLog(10)
+
+	log.Record("POST /api/v1/toggles/error-rate", "admin", map[string]interface{}{"rate": 0.5})
+
+	entries := log.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	if entries[0].Action != "POST /api/v1/toggles/error-rate" {
+		t.Errorf("Expected action to be recorded, got %s", entries[0].Action)
+	}
+	if entries[0].TokenName != "admin" {
+		t.Errorf("Expected token name 'admin', got %s", entries[0].TokenName)
+	}
+	if entries[0].Params["rate"] != 0.5 {
+		t.Errorf("Expected rate param 0.5, got %v", entries[0].Params["rate"])
+	}
+	if entries[0].Timestamp.IsZero() {
+		t.Error("Expected timestamp to be set")
+	}
+}
+
+func TestLog_RingBufferOverwritesOldest(t *testing.T) {
+	log := NewLog(3)
+
+	for _, action := range []string{"a", "b", "c", "d", "e"} {
+		log.Record(action, "admin", nil)
+	}
+
+	entries := log.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	for i, want := range []string{"c", "d", "e"} {
+		if entries[i].Action != want {
+			t.Errorf("Entry %d: expected action %s, got %s", i, want, entries[i].Action)
+		}
+	}
+}
+
+func TestLog_RedactsSecrets(t *testing.T) {
+	log := NewLog(10)
+
+	log.Record("POST /api/v1/admin/token", "admin", map[string]interface{}{
+		"token":   "super-secret",
+		"enabled": true,
+		"nested": map[string]interface{}{
+			"Password": "hunter2",
+		},
+	})
+
+	params := log.Entries()[0].Params
+	if params["token"] != redactedValue {
+		t.Errorf("Expected token to be redacted, got %v", params["token"])
+	}
+	if params["enabled"] != true {
+		t.Errorf("Expected non-secret param to be kept, got %v", params["enabled"])
+	}
+	if nested := params["nested"].(map[string]interface{}); nested["Password"] != redactedValue {
+		t.Errorf("Expected nested password to be redacted, got %v", nested["Password"])
+	}
+}
+
+func TestHandler(t *testing.T) {
+	log := NewLog(10)
+	log.Record("POST /api/v1/toggles/readiness", "admin", map[string]interface{}{"force_failure": true})
+
+	req := httptest.NewRequest("GET", "/api/v1/audit", nil)
+	w := httptest.NewRecorder()
+
+	Handler(log)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", w.Header().Get("Content-Type"))
+	}
+
+	var response struct {
+		Entries []Entry `json:"entries"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Entries) != 1 || response.Entries[0].Action != "POST /api/v1/toggles/readiness" {
+		t.Errorf("Expected recorded entry in response, got %+v", response.Entries)
+	}
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the application
@@ -11,31 +17,415 @@ type Config struct {
 	AdminToken  string
 	LogLevel    string
 	Environment string
+
+	// AdminTokens lists every accepted admin token, so a new token can be
+	// rolled out before the old one is retired. When empty, AdminToken is used.
+	AdminTokens []string
+
+	// Paths the liveness, readiness and metrics endpoints are served on
+	LivenessPath  string
+	ReadinessPath string
+	MetricsPath   string
+
+	// ProtectMetrics requires the admin bearer token on /metrics
+	ProtectMetrics bool
+
+	// Defaults for /api/v1/work when ms/jitter are not given
+	DefaultWorkMs     int
+	DefaultWorkJitter int
+
+	// EnableResetEndpoint mounts GET /api/v1/reset, which drops connections
+	// without a response
+	EnableResetEndpoint bool
+
+	// EnablePanicEndpoint mounts GET /api/v1/panic, which panics to exercise
+	// panic recovery and alerting
+	EnablePanicEndpoint bool
+
+	// ProbeAllowedHosts lists the host names, IPs and CIDR ranges
+	// GET /api/v1/probe may reach; the endpoint is disabled when empty
+	ProbeAllowedHosts []string
+
+	// StrictQueryParams rejects unknown query parameters on /api/v1/work
+	StrictQueryParams bool
+
+	// Fraction of requests whose bodies are sampled into logs, and the
+	// maximum number of bytes logged per body
+	LogBodySampleRate float64
+	LogBodyMaxBytes   int
+
+	// LogRequestStart logs a "Request started" line before each request in
+	// addition to the completion line; LogAccessFields lists the optional
+	// fields (query, bytes, remote_addr, client_ip, user_agent) added to the
+	// completion line
+	LogRequestStart bool
+	LogAccessFields []string
+
+	// Log sampling: per second, the first LogSampleInitial entries with the
+	// same level and message are logged, then every LogSampleThereafter-th.
+	// When unset, production samples 100/100 and other levels do not sample.
+	LogSampleInitial    int
+	LogSampleThereafter int
+
+	// RequestTimeout bounds most requests, while /api/v1/work and
+	// /api/v1/work/batch get the longer WorkTimeout; 0 disables either
+	RequestTimeout time.Duration
+	WorkTimeout    time.Duration
+
+	// Route patterns registered without the request timeouts (e.g. SSE streams)
+	TimeoutExemptRoutes []string
+
+	// PrometheusURL is checked for readiness when set
+	PrometheusURL string
+
+	// HTTPDurationBuckets are the http_request_duration_seconds bucket
+	// boundaries; Prometheus defaults are used when empty
+	HTTPDurationBuckets []float64
+
+	// MetricsScrapeTimeout bounds how long a /metrics scrape may take
+	MetricsScrapeTimeout time.Duration
+
+	// DataDir must be writable for readiness when set
+	DataDir string
+
+	// TLSCertFile and TLSKeyFile switch the server to HTTPS when both are
+	// set. MinTLSVersion ("1.2" or "1.3") is the oldest protocol accepted.
+	TLSCertFile   string
+	TLSKeyFile    string
+	MinTLSVersion string
+
+	// ReadinessCommand is run as a readiness check, failing readiness on a
+	// non-zero exit. Running it also requires EnableCommandCheck, so a
+	// config value alone cannot make the service execute commands.
+	ReadinessCommand        []string
+	ReadinessCommandTimeout time.Duration
+	EnableCommandCheck      bool
+
+	// ReadinessCacheTTL reuses a readiness result for this long so frequent
+	// probes do not run every dependency check; 0 disables caching
+	ReadinessCacheTTL time.Duration
+
+	// ShutdownTimeout bounds the graceful shutdown, and ShutdownPollInterval
+	// is how often it checks whether in-flight work jobs have finished
+	ShutdownTimeout      time.Duration
+	ShutdownPollInterval time.Duration
+
+	// ShutdownWebhookURL is notified when a graceful shutdown starts, when set
+	ShutdownWebhookURL string
+
+	// OtelExporterOTLPEndpoint is the OTLP/HTTP collector that request spans
+	// are exported to; tracing is disabled when empty
+	OtelExporterOTLPEndpoint string
+
+	// IdempotencyTTL is how long responses are replayed for a repeated Idempotency-Key
+	IdempotencyTTL time.Duration
+
+	// Browser origins allowed to call /api/v1 routes ("*" for any)
+	CORSAllowedOrigins []string
+
+	// Per-client rate limit for /api/v1 routes; disabled when RateLimitRPS is 0
+	RateLimitRPS   float64
+	RateLimitBurst int
+
+	// MaxConcurrentRequests caps requests served at once on /api/v1 routes;
+	// 0 disables the cap
+	MaxConcurrentRequests int
+
+	// MaxURLLength caps the length of request URLs; 0 disables the cap
+	MaxURLLength int
+
+	// MaxStreamConnections caps concurrent streaming (SSE) connections; 0 disables the cap
+	MaxStreamConnections int
+
+	// AdminAllowedCIDRs restricts the admin toggle routes to clients in these
+	// ranges; any address is allowed when empty
+	AdminAllowedCIDRs []string
+
+	// TrustProxy resolves client addresses from X-Forwarded-For and X-Real-IP
+	// for rate limiting, the admin allowlist and logs, for deployments behind
+	// a load balancer that sets them
+	TrustProxy bool
+
+	// MaxBodyBytes caps request bodies accepted by the admin toggle endpoints
+	MaxBodyBytes int64
+
+	// ConfigExpandPolicy decides how ${VAR} references to undefined variables
+	// in CONFIG_FILE values are handled
+	ConfigExpandPolicy ExpansionPolicy
+
+	// Warnings lists problems that did not prevent loading, such as unknown
+	// config file keys, for the caller to log once logging is set up
+	Warnings []string
 }
 
-// Load reads configuration from environment variables with sensible defaults
+// Shutdown defaults, used when the configured values are missing or inconsistent
+const (
+	defaultShutdownTimeout      = 30 * time.Second
+	defaultShutdownPollInterval = 1 * time.Second
+)
+
+// Load reads configuration from environment variables with sensible defaults.
+// When CONFIG_FILE is set, values from that file are used as well (see LoadFromFile).
 func Load() (*Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return LoadFromFile(path)
+	}
+	return load(newSource(nil))
+}
+
+// load builds the configuration from src and validates it
+func load(src *source) (*Config, error) {
 	cfg := &Config{
-		Port:        getEnv("APP_PORT", "8080"),
-		AdminToken:  getEnv("ADMIN_TOKEN", "changeme"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-		Environment: getEnv("ENVIRONMENT", "development"),
+		Port:        src.getEnv("APP_PORT", "8080"),
+		AdminToken:  src.getEnv("ADMIN_TOKEN", defaultAdminToken),
+		LogLevel:    src.getEnv("LOG_LEVEL", "info"),
+		Environment: src.getEnv("ENVIRONMENT", "development"),
+
+		AdminTokens: src.getEnvList("ADMIN_TOKENS", nil),
+
+		LivenessPath:  src.getEnv("LIVENESS_PATH", "/healthz"),
+		ReadinessPath: src.getEnv("READINESS_PATH", "/readyz"),
+		MetricsPath:   src.getEnv("METRICS_PATH", "/metrics"),
+
+		ProtectMetrics: src.getEnvBool("PROTECT_METRICS", false),
+
+		DefaultWorkMs:     src.getEnvInt("DEFAULT_WORK_MS", 100),
+		DefaultWorkJitter: src.getEnvInt("DEFAULT_WORK_JITTER", 0),
+
+		EnableResetEndpoint: src.getEnvBool("ENABLE_RESET_ENDPOINT", false),
+		EnablePanicEndpoint: src.getEnvBool("ENABLE_PANIC_ENDPOINT", false),
+
+		ProbeAllowedHosts: src.getEnvList("PROBE_ALLOWED_HOSTS", nil),
+
+		StrictQueryParams: src.getEnvBool("STRICT_QUERY_PARAMS", false),
+
+		LogBodySampleRate: src.getEnvFloat("LOG_BODY_SAMPLE_RATE", 0),
+		LogBodyMaxBytes:   src.getEnvInt("LOG_BODY_MAX_BYTES", 1024),
+
+		LogRequestStart: src.getEnvBool("LOG_REQUEST_START", true),
+		LogAccessFields: src.getEnvList("LOG_ACCESS_FIELDS", []string{"bytes"}),
+
+		LogSampleInitial:    src.getEnvInt("LOG_SAMPLE_INITIAL", 0),
+		LogSampleThereafter: src.getEnvInt("LOG_SAMPLE_THEREAFTER", 0),
+
+		RequestTimeout: src.getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		WorkTimeout:    src.getEnvDuration("WORK_TIMEOUT", 5*time.Minute),
+
+		TimeoutExemptRoutes: src.getEnvList("TIMEOUT_EXEMPT_ROUTES", nil),
+
+		PrometheusURL: src.getEnv("PROMETHEUS_URL", ""),
+
+		HTTPDurationBuckets: src.getEnvFloatList("HTTP_DURATION_BUCKETS", nil),
+
+		MetricsScrapeTimeout: src.getEnvDuration("METRICS_SCRAPE_TIMEOUT", 10*time.Second),
+
+		DataDir: src.getEnv("DATA_DIR", ""),
+
+		TLSCertFile:   src.getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:    src.getEnv("TLS_KEY_FILE", ""),
+		MinTLSVersion: src.getEnv("MIN_TLS_VERSION", "1.2"),
+
+		ReadinessCommand:        strings.Fields(src.getEnv("READINESS_COMMAND", "")),
+		ReadinessCommandTimeout: src.getEnvDuration("READINESS_COMMAND_TIMEOUT", 5*time.Second),
+		EnableCommandCheck:      src.getEnvBool("ENABLE_COMMAND_CHECK", false),
+
+		ReadinessCacheTTL: src.getEnvDuration("READINESS_CACHE_TTL", 0),
+
+		ShutdownTimeout:      src.getEnvDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		ShutdownPollInterval: src.getEnvDuration("SHUTDOWN_POLL_INTERVAL", defaultShutdownPollInterval),
+		ShutdownWebhookURL:   src.getEnv("SHUTDOWN_WEBHOOK_URL", ""),
+
+		OtelExporterOTLPEndpoint: src.getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+
+		IdempotencyTTL: src.getEnvDuration("IDEMPOTENCY_TTL", 0),
+
+		CORSAllowedOrigins: src.getEnvList("CORS_ALLOWED_ORIGINS", nil),
+
+		RateLimitRPS:   src.getEnvFloat("RATE_LIMIT_RPS", 0),
+		RateLimitBurst: src.getEnvInt("RATE_LIMIT_BURST", 10),
+
+		MaxConcurrentRequests: src.getEnvInt("MAX_CONCURRENT_REQUESTS", 0),
+
+		MaxURLLength: src.getEnvInt("MAX_URL_LENGTH", 8192),
+
+		MaxStreamConnections: src.getEnvInt("MAX_STREAM_CONNECTIONS", 100),
+
+		MaxBodyBytes: int64(src.getEnvInt("MAX_BODY_BYTES", 64*1024)),
+
+		AdminAllowedCIDRs: src.getEnvList("ADMIN_ALLOWED_CIDRS", nil),
+		TrustProxy:        src.getEnvBool("TRUST_PROXY", false),
+
+		ConfigExpandPolicy: ExpansionPolicy(src.getEnv("CONFIG_EXPAND_POLICY", string(ExpandStrict))),
+	}
+
+	// The drain must be able to poll at least once before the deadline
+	if cfg.ShutdownTimeout <= 0 || cfg.ShutdownPollInterval <= 0 || cfg.ShutdownPollInterval >= cfg.ShutdownTimeout {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+		cfg.ShutdownPollInterval = defaultShutdownPollInterval
+	}
+
+	cfg.Warnings = src.unknownKeys()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
 	return cfg, nil
 }
 
+// defaultAdminToken is the placeholder token that must not be used in production
+const defaultAdminToken = "changeme"
+
+// validLogLevels are the LOG_LEVEL values the logger understands
+var validLogLevels = map[string]bool{
+	"debug":      true,
+	"info":       true,
+	"warn":       true,
+	"error":      true,
+	"production": true,
+}
+
+// validAccessLogFields are the optional LOG_ACCESS_FIELDS the access log can add
+var validAccessLogFields = map[string]bool{
+	"query":       true,
+	"bytes":       true,
+	"remote_addr": true,
+	"client_ip":   true,
+	"user_agent":  true,
+}
+
+// Validate reports the first setting that would make the application unsafe
+// or unable to start
+func (c *Config) Validate() error {
+	port, err := strconv.Atoi(c.Port)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("APP_PORT %q must be a port number between 1 and 65535", c.Port)
+	}
+
+	if !validLogLevels[c.LogLevel] {
+		return fmt.Errorf("LOG_LEVEL %q must be one of debug, info, warn, error, production", c.LogLevel)
+	}
+
+	for _, field := range c.LogAccessFields {
+		if !validAccessLogFields[field] {
+			return fmt.Errorf("LOG_ACCESS_FIELDS entry %q must be one of query, bytes, remote_addr, client_ip, user_agent", field)
+		}
+	}
+
+	if c.LogSampleInitial < 0 || c.LogSampleThereafter < 0 || (c.LogSampleInitial > 0) != (c.LogSampleThereafter > 0) {
+		return errors.New("LOG_SAMPLE_INITIAL and LOG_SAMPLE_THEREAFTER must be set together as positive numbers")
+	}
+
+	for key, path := range map[string]string{
+		"LIVENESS_PATH":  c.LivenessPath,
+		"READINESS_PATH": c.ReadinessPath,
+		"METRICS_PATH":   c.MetricsPath,
+	} {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("%s %q must start with /", key, path)
+		}
+	}
+
+	for _, entry := range c.ProbeAllowedHosts {
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return fmt.Errorf("PROBE_ALLOWED_HOSTS entry %q is not a valid CIDR range", entry)
+			}
+		}
+	}
+
+	for _, entry := range c.AdminAllowedCIDRs {
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("ADMIN_ALLOWED_CIDRS entry %q is not a valid CIDR range", entry)
+		}
+	}
+
+	for i := 1; i < len(c.HTTPDurationBuckets); i++ {
+		if c.HTTPDurationBuckets[i] <= c.HTTPDurationBuckets[i-1] {
+			return fmt.Errorf("HTTP_DURATION_BUCKETS %v must be in increasing order", c.HTTPDurationBuckets)
+		}
+	}
+
+	if c.DefaultWorkMs < 0 || c.DefaultWorkJitter < 0 {
+		return errors.New("DEFAULT_WORK_MS and DEFAULT_WORK_JITTER must not be negative")
+	}
+
+	if c.RequestTimeout < 0 || c.WorkTimeout < 0 {
+		return errors.New("REQUEST_TIMEOUT and WORK_TIMEOUT must not be negative")
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return errors.New("MAX_CONCURRENT_REQUESTS must not be negative")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.MinTLSVersion != "" {
+		if _, ok := tlsVersions[c.MinTLSVersion]; !ok {
+			return fmt.Errorf("MIN_TLS_VERSION %q must be one of 1.2, 1.3", c.MinTLSVersion)
+		}
+	}
+
+	if c.ConfigExpandPolicy != "" && !validExpansionPolicies[c.ConfigExpandPolicy] {
+		return fmt.Errorf("CONFIG_EXPAND_POLICY %q must be one of strict, literal", c.ConfigExpandPolicy)
+	}
+
+	if len(c.ReadinessCommand) > 0 && !c.EnableCommandCheck {
+		return errors.New("READINESS_COMMAND requires ENABLE_COMMAND_CHECK=true")
+	}
+	if c.ReadinessCacheTTL < 0 {
+		return errors.New("READINESS_CACHE_TTL must not be negative")
+	}
+
+	if c.Environment == "production" {
+		for _, token := range c.ValidAdminTokens() {
+			if strings.TrimSpace(token) == "" {
+				return errors.New("ADMIN_TOKEN must be set in production")
+			}
+			if token == defaultAdminToken {
+				return errors.New("ADMIN_TOKEN must not be the default \"changeme\" in production")
+			}
+		}
+	}
+
+	return nil
+}
+
+// tlsVersions maps the accepted MIN_TLS_VERSION values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSMinVersion returns the crypto/tls version for MinTLSVersion, defaulting
+// to TLS 1.2
+func (c *Config) TLSMinVersion() uint16 {
+	if version, ok := tlsVersions[c.MinTLSVersion]; ok {
+		return version
+	}
+	return tls.VersionTLS12
+}
+
+// ValidAdminTokens returns the accepted admin tokens: AdminTokens when set,
+// otherwise the single AdminToken
+func (c *Config) ValidAdminTokens() []string {
+	if len(c.AdminTokens) > 0 {
+		return c.AdminTokens
+	}
+	return []string{c.AdminToken}
+}
+
 // getEnv gets an environment variable with a fallback default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+func (s *source) getEnv(key, defaultValue string) string {
+	if value := s.lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
 // getEnvBool gets a boolean environment variable with a fallback default value
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
+func (s *source) getEnvBool(key string, defaultValue bool) bool {
+	if value := s.lookup(key); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
 			return parsed
 		}
@@ -44,11 +434,66 @@ func getEnvBool(key string, defaultValue bool) bool {
 }
 
 // getEnvInt gets an integer environment variable with a fallback default value
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+func (s *source) getEnvInt(key string, defaultValue int) int {
+	if value := s.lookup(key); value != "" {
 		if parsed, err := strconv.Atoi(value); err == nil {
 			return parsed
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvFloat gets a float environment variable with a fallback default value
+func (s *source) getEnvFloat(key string, defaultValue float64) float64 {
+	if value := s.lookup(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "10s") with a fallback default value
+func (s *source) getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := s.lookup(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList gets a comma-separated environment variable with a fallback default value
+func (s *source) getEnvList(key string, defaultValue []string) []string {
+	value := s.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvFloatList gets a comma-separated list of floats with a fallback
+// default value, used when any item fails to parse
+func (s *source) getEnvFloatList(key string, defaultValue []float64) []float64 {
+	items := s.getEnvList(key, nil)
+	if len(items) == 0 {
+		return defaultValue
+	}
+
+	list := make([]float64, 0, len(items))
+	for _, item := range items {
+		parsed, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return defaultValue
+		}
+		list = append(list, parsed)
+	}
+	return list
+}
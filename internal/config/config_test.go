@@ -0,0 +1,221 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad_ShutdownDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Expected shutdown timeout 30s, got %v", cfg.ShutdownTimeout)
+	}
+	if cfg.ShutdownPollInterval != time.Second {
+		t.Errorf("Expected shutdown poll interval 1s, got %v", cfg.ShutdownPollInterval)
+	}
+}
+
+func TestLoad_ShutdownFromEnv(t *testing.T) {
+	t.Setenv("SHUTDOWN_TIMEOUT", "2m")
+	t.Setenv("SHUTDOWN_POLL_INTERVAL", "250ms")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.ShutdownTimeout != 2*time.Minute {
+		t.Errorf("Expected shutdown timeout 2m, got %v", cfg.ShutdownTimeout)
+	}
+	if cfg.ShutdownPollInterval != 250*time.Millisecond {
+		t.Errorf("Expected shutdown poll interval 250ms, got %v", cfg.ShutdownPollInterval)
+	}
+}
+
+func TestLoad_ShutdownFallsBackToDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      string
+		pollInterval string
+	}{
+		{name: "unparseable timeout", timeout: "soon", pollInterval: "1s"},
+		{name: "negative poll interval", timeout: "10s", pollInterval: "-1s"},
+		{name: "poll interval not below timeout", timeout: "5s", pollInterval: "5s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHUTDOWN_TIMEOUT", tt.timeout)
+			t.Setenv("SHUTDOWN_POLL_INTERVAL", tt.pollInterval)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			if cfg.ShutdownTimeout != 30*time.Second || cfg.ShutdownPollInterval != time.Second {
+				t.Errorf("Expected defaults 30s/1s, got %v/%v", cfg.ShutdownTimeout, cfg.ShutdownPollInterval)
+			}
+		})
+	}
+}
+
+func TestLoad_AdminTokens(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "single")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := cfg.ValidAdminTokens(); len(got) != 1 || got[0] != "single" {
+		t.Errorf("Expected fallback to ADMIN_TOKEN, got %v", got)
+	}
+
+	t.Setenv("ADMIN_TOKENS", "current, next")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := cfg.ValidAdminTokens(); len(got) != 2 || got[0] != "current" || got[1] != "next" {
+		t.Errorf("Expected tokens from ADMIN_TOKENS, got %v", got)
+	}
+}
+
+func TestLoad_HTTPDurationBuckets(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []float64
+	}{
+		{name: "unset", value: "", expected: nil},
+		{name: "valid", value: "0.1, 0.5,1,5,30", expected: []float64{0.1, 0.5, 1, 5, 30}},
+		{name: "unparseable", value: "0.1,fast,1", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HTTP_DURATION_BUCKETS", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if fmt.Sprint(cfg.HTTPDurationBuckets) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected buckets %v, got %v", tt.expected, cfg.HTTPDurationBuckets)
+			}
+		})
+	}
+}
+
+func TestLoad_ReadinessCommand(t *testing.T) {
+	t.Setenv("READINESS_COMMAND", "pg_isready -h db  -t 2")
+	t.Setenv("ENABLE_COMMAND_CHECK", "true")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if fmt.Sprint(cfg.ReadinessCommand) != "[pg_isready -h db -t 2]" {
+		t.Errorf("Expected the command split into arguments, got %q", cfg.ReadinessCommand)
+	}
+	if cfg.ReadinessCommandTimeout != 5*time.Second {
+		t.Errorf("Expected the default 5s timeout, got %v", cfg.ReadinessCommandTimeout)
+	}
+}
+
+func TestConfig_TLSMinVersion(t *testing.T) {
+	tests := map[string]uint16{
+		"":    tls.VersionTLS12,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	for value, expected := range tests {
+		cfg := &Config{MinTLSVersion: value}
+		if got := cfg.TLSMinVersion(); got != expected {
+			t.Errorf("MinTLSVersion %q: expected %#x, got %#x", value, expected, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{Port: "8080", AdminToken: "s3cret", LogLevel: "info", Environment: "production"}
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		errMsg string
+	}{
+		{name: "non-numeric port", modify: func(c *Config) { c.Port = "http" }, errMsg: "APP_PORT"},
+		{name: "port zero", modify: func(c *Config) { c.Port = "0" }, errMsg: "APP_PORT"},
+		{name: "port too large", modify: func(c *Config) { c.Port = "65536" }, errMsg: "APP_PORT"},
+		{name: "relative readiness path", modify: func(c *Config) { c.ReadinessPath = "ready" }, errMsg: "READINESS_PATH"},
+		{name: "invalid probe CIDR", modify: func(c *Config) { c.ProbeAllowedHosts = []string{"10.0.0.0/33"} }, errMsg: "PROBE_ALLOWED_HOSTS"},
+		{name: "invalid admin CIDR", modify: func(c *Config) { c.AdminAllowedCIDRs = []string{"10.0.0.1"} }, errMsg: "ADMIN_ALLOWED_CIDRS"},
+		{name: "unsorted buckets", modify: func(c *Config) { c.HTTPDurationBuckets = []float64{1, 0.5} }, errMsg: "HTTP_DURATION_BUCKETS"},
+		{name: "negative default work duration", modify: func(c *Config) { c.DefaultWorkMs = -1 }, errMsg: "DEFAULT_WORK_MS"},
+		{name: "negative work timeout", modify: func(c *Config) { c.WorkTimeout = -time.Second }, errMsg: "WORK_TIMEOUT"},
+		{name: "negative concurrency limit", modify: func(c *Config) { c.MaxConcurrentRequests = -1 }, errMsg: "MAX_CONCURRENT_REQUESTS"},
+		{name: "negative readiness cache TTL", modify: func(c *Config) { c.ReadinessCacheTTL = -time.Second }, errMsg: "READINESS_CACHE_TTL"},
+		{name: "TLS cert without key", modify: func(c *Config) { c.TLSCertFile = "server.crt" }, errMsg: "TLS_KEY_FILE"},
+		{name: "unsupported TLS version", modify: func(c *Config) { c.MinTLSVersion = "1.1" }, errMsg: "MIN_TLS_VERSION"},
+		{name: "unknown expand policy", modify: func(c *Config) { c.ConfigExpandPolicy = "lenient" }, errMsg: "CONFIG_EXPAND_POLICY"},
+		{name: "readiness command without opt-in", modify: func(c *Config) { c.ReadinessCommand = []string{"true"} }, errMsg: "ENABLE_COMMAND_CHECK"},
+		{name: "unknown access log field", modify: func(c *Config) { c.LogAccessFields = []string{"bytes", "cookies"} }, errMsg: "LOG_ACCESS_FIELDS"},
+		{name: "log sampling without thereafter", modify: func(c *Config) { c.LogSampleInitial = 10 }, errMsg: "LOG_SAMPLE_THEREAFTER"},
+		{name: "negative log sampling", modify: func(c *Config) { c.LogSampleInitial, c.LogSampleThereafter = -1, 10 }, errMsg: "LOG_SAMPLE_INITIAL"},
+		{name: "unknown log level", modify: func(c *Config) { c.LogLevel = "verbose" }, errMsg: "LOG_LEVEL"},
+		{name: "empty token in production", modify: func(c *Config) { c.AdminToken = "" }, errMsg: "ADMIN_TOKEN"},
+		{name: "default token in production", modify: func(c *Config) { c.AdminToken = "changeme" }, errMsg: "ADMIN_TOKEN"},
+		{name: "default among admin tokens in production", modify: func(c *Config) { c.AdminTokens = []string{"s3cret", "changeme"} }, errMsg: "ADMIN_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected a validation error")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error mentioning %s, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidate_DefaultTokenOutsideProduction(t *testing.T) {
+	cfg := &Config{Port: "8080", AdminToken: "changeme", LogLevel: "debug", Environment: "development"}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the default token to be allowed outside production, got %v", err)
+	}
+}
+
+func TestLoad_InvalidConfig(t *testing.T) {
+	t.Setenv("APP_PORT", "99999")
+
+	cfg, err := Load()
+	if err == nil {
+		t.Fatal("Expected Load to fail for an out of range port")
+	}
+	if cfg != nil {
+		t.Error("Expected no config on error")
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ExpansionPolicy controls how ${VAR} references to undefined variables are handled
+type ExpansionPolicy string
+
+const (
+	// ExpandLiteral leaves references to undefined variables as written
+	ExpandLiteral ExpansionPolicy = "literal"
+	// ExpandStrict fails when a referenced variable is undefined
+	ExpandStrict ExpansionPolicy = "strict"
+)
+
+// validExpansionPolicies are the CONFIG_EXPAND_POLICY values expandEnv understands
+var validExpansionPolicies = map[ExpansionPolicy]bool{
+	ExpandLiteral: true,
+	ExpandStrict:  true,
+}
+
+// envReference matches ${VAR} references in config values
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in value with the environment variable's
+// value, so secrets can stay in the environment while structure lives in a file
+func expandEnv(value string, policy ExpansionPolicy) (string, error) {
+	var missing string
+
+	expanded := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		if missing == "" {
+			missing = name
+		}
+		return ref
+	})
+
+	if missing != "" && policy == ExpandStrict {
+		return "", fmt.Errorf("undefined environment variable %q", missing)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("TEST_ADMIN_TOKEN", "s3cret")
+
+	// Values as they would appear in a config file
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("admin_token: \"${TEST_ADMIN_TOKEN}\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	expanded, err := expandEnv(string(contents), ExpandStrict)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expanded != "admin_token: \"s3cret\"\n" {
+		t.Errorf("Expected token to be expanded, got %q", expanded)
+	}
+}
+
+func TestExpandEnv_UndefinedVariable(t *testing.T) {
+	os.Unsetenv("TEST_UNDEFINED_VAR")
+
+	tests := []struct {
+		name      string
+		policy    ExpansionPolicy
+		expected  string
+		expectErr bool
+	}{
+		{name: "literal policy keeps reference", policy: ExpandLiteral, expected: "token-${TEST_UNDEFINED_VAR}"},
+		{name: "strict policy errors", policy: ExpandStrict, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := expandEnv("token-${TEST_UNDEFINED_VAR}", tt.policy)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got %q", expanded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if expanded != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, expanded)
+			}
+		})
+	}
+}
+
+func TestExpandEnv_EmptyValueIsDefined(t *testing.T) {
+	t.Setenv("TEST_EMPTY_VAR", "")
+
+	expanded, err := expandEnv("[${TEST_EMPTY_VAR}]", ExpandStrict)
+	if err != nil {
+		t.Fatalf("Expected empty variable to count as defined, got %v", err)
+	}
+	if expanded != "[]" {
+		t.Errorf("Expected empty expansion, got %q", expanded)
+	}
+}
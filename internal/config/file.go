@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves settings by environment variable name, falling back to
+// values from a config file. File keys are the lower-cased variable names
+// (e.g. admin_token for ADMIN_TOKEN).
+type source struct {
+	file map[string]string
+	used map[string]bool
+}
+
+func newSource(file map[string]string) *source {
+	return &source{file: file, used: make(map[string]bool)}
+}
+
+// lookup returns the environment variable key, or the file value when the
+// variable is unset or empty
+func (s *source) lookup(key string) string {
+	s.used[key] = true
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return s.file[key]
+}
+
+// unknownKeys returns a warning for each file key that no setting looked up
+func (s *source) unknownKeys() []string {
+	var warnings []string
+	for key := range s.file {
+		if !s.used[key] {
+			warnings = append(warnings, fmt.Sprintf("unknown config file key %q ignored", strings.ToLower(key)))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// LoadFromFile reads configuration from a YAML or JSON file, with environment
+// variables overriding file values. String values may reference environment
+// variables as ${VAR}; CONFIG_EXPAND_POLICY decides whether referencing an
+// undefined variable is an error (strict, the default) or left as written
+// (literal). Unknown keys are reported in Config.Warnings.
+func LoadFromFile(path string) (*Config, error) {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return load(newSource(values))
+}
+
+// readConfigFile parses a flat YAML or JSON object into settings keyed by
+// environment variable name, expanding ${VAR} references under the policy
+// set by CONFIG_EXPAND_POLICY in the environment or the file itself. Lists
+// are joined with commas.
+func readConfigFile(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		str, err := fileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("config file key %q: %w", key, err)
+		}
+		values[strings.ToUpper(key)] = str
+	}
+
+	policy := ExpansionPolicy(newSource(values).getEnv("CONFIG_EXPAND_POLICY", string(ExpandStrict)))
+	if !validExpansionPolicies[policy] {
+		return nil, fmt.Errorf("CONFIG_EXPAND_POLICY %q must be one of strict, literal", policy)
+	}
+
+	for key, str := range values {
+		expanded, err := expandEnv(str, policy)
+		if err != nil {
+			return nil, fmt.Errorf("config file key %q: %w", strings.ToLower(key), err)
+		}
+		values[key] = expanded
+	}
+	return values, nil
+}
+
+// fileValue renders a scalar or list of scalars the way it would be written
+// in the corresponding environment variable
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case map[string]interface{}:
+		return "", fmt.Errorf("nested objects are not supported")
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
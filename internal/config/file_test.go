@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes contents to a temp file with the given name and returns its path
+func writeConfigFile(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFromFile_YAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+app_port: 9090
+log_level: debug
+protect_metrics: true
+log_body_sample_rate: 0.25
+metrics_scrape_timeout: 3s
+timeout_exempt_routes:
+  - /api/v1/stream
+  - /api/v1/events
+`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	if cfg.Port != "9090" || cfg.LogLevel != "debug" || !cfg.ProtectMetrics {
+		t.Errorf("Expected file values, got port %s, log level %s, protect metrics %v", cfg.Port, cfg.LogLevel, cfg.ProtectMetrics)
+	}
+	if cfg.LogBodySampleRate != 0.25 || cfg.MetricsScrapeTimeout != 3*time.Second {
+		t.Errorf("Expected sample rate 0.25 and timeout 3s, got %v and %v", cfg.LogBodySampleRate, cfg.MetricsScrapeTimeout)
+	}
+	if strings.Join(cfg.TimeoutExemptRoutes, ",") != "/api/v1/stream,/api/v1/events" {
+		t.Errorf("Expected exempt routes from the list, got %v", cfg.TimeoutExemptRoutes)
+	}
+
+	// Settings missing from the file keep their defaults
+	if cfg.DefaultWorkMs != 100 {
+		t.Errorf("Expected default work ms 100, got %d", cfg.DefaultWorkMs)
+	}
+}
+
+func TestLoadFromFile_JSON(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"app_port": "9091", "environment": "staging"}`)
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	if cfg.Port != "9091" || cfg.Environment != "staging" {
+		t.Errorf("Expected file values, got port %s, environment %s", cfg.Port, cfg.Environment)
+	}
+}
+
+func TestLoadFromFile_EnvOverridesFile(t *testing.T) {
+	t.Setenv("APP_PORT", "7070")
+	path := writeConfigFile(t, "config.yaml", "app_port: 9090\nlog_level: warn\n")
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	if cfg.Port != "7070" {
+		t.Errorf("Expected the environment to win, got port %s", cfg.Port)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("Expected log level from the file, got %s", cfg.LogLevel)
+	}
+}
+
+func TestLoadFromFile_ExpandsEnvReferences(t *testing.T) {
+	t.Setenv("TEST_ADMIN_TOKEN", "s3cret")
+	path := writeConfigFile(t, "config.yaml", "admin_token: ${TEST_ADMIN_TOKEN}\n")
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	if cfg.AdminToken != "s3cret" {
+		t.Errorf("Expected the token to be expanded, got %s", cfg.AdminToken)
+	}
+}
+
+func TestLoadFromFile_ExpandPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		contents  string
+		expectErr bool
+		expected  string
+	}{
+		{name: "strict by default", contents: "admin_token: ${TEST_UNDEFINED_CONFIG_VAR}\n", expectErr: true},
+		{name: "strict from env", env: "strict", contents: "admin_token: ${TEST_UNDEFINED_CONFIG_VAR}\n", expectErr: true},
+		{name: "literal from env", env: "literal", contents: "admin_token: ${TEST_UNDEFINED_CONFIG_VAR}\n", expected: "${TEST_UNDEFINED_CONFIG_VAR}"},
+		{name: "literal from file", contents: "config_expand_policy: literal\nadmin_token: ${TEST_UNDEFINED_CONFIG_VAR}\n", expected: "${TEST_UNDEFINED_CONFIG_VAR}"},
+		{name: "env overrides file", env: "strict", contents: "config_expand_policy: literal\nadmin_token: ${TEST_UNDEFINED_CONFIG_VAR}\n", expectErr: true},
+		{name: "unknown policy", env: "lenient", contents: "admin_token: s3cret\n", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_EXPAND_POLICY", tt.env)
+			path := writeConfigFile(t, "config.yaml", tt.contents)
+
+			cfg, err := LoadFromFile(path)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("Expected LoadFromFile to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFromFile failed: %v", err)
+			}
+
+			if cfg.AdminToken != tt.expected {
+				t.Errorf("Expected admin token %q, got %q", tt.expected, cfg.AdminToken)
+			}
+			if cfg.ConfigExpandPolicy != ExpandLiteral {
+				t.Errorf("Expected policy %q, got %q", ExpandLiteral, cfg.ConfigExpandPolicy)
+			}
+			if len(cfg.Warnings) != 0 {
+				t.Errorf("Expected no warnings, got %v", cfg.Warnings)
+			}
+		})
+	}
+}
+
+func TestLoadFromFile_UnknownKeysWarn(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "app_port: 9090\nlog_levle: debug\n")
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("Expected unknown keys not to fail loading, got %v", err)
+	}
+
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "log_levle") {
+		t.Errorf("Expected one warning about log_levle, got %v", cfg.Warnings)
+	}
+}
+
+func TestLoadFromFile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{name: "malformed YAML", contents: "app_port: [9090\n"},
+		{name: "malformed JSON", contents: `{"app_port": 9090`},
+		{name: "not an object", contents: "- 9090\n"},
+		{name: "nested object", contents: "app_port:\n  value: 9090\n"},
+		{name: "undefined variable", contents: "admin_token: ${TEST_UNDEFINED_CONFIG_VAR}\n"},
+		{name: "invalid value", contents: "app_port: 99999\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, "config.yaml", tt.contents)
+
+			if _, err := LoadFromFile(path); err == nil {
+				t.Error("Expected LoadFromFile to fail")
+			}
+		})
+	}
+
+	if _, err := LoadFromFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected LoadFromFile to fail for a missing file")
+	}
+}
+
+func TestLoad_ConfigFileEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.yaml", "app_port: 9092\n"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != "9092" {
+		t.Errorf("Expected port from CONFIG_FILE, got %s", cfg.Port)
+	}
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxCommandOutput bounds how much command output is quoted in a failure
+const maxCommandOutput = 256
+
+// CommandCheck returns a check that runs cmd with args and fails if it exits
+// non-zero. The command is killed after timeout or when the context passed to
+// it ends, whichever comes first. The command runs with the service's
+// privileges, so only configure commands from trusted sources.
+func CommandCheck(cmd string, args []string, timeout time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var output bytes.Buffer
+		c := exec.CommandContext(ctx, cmd, args...)
+		c.Stdout = &output
+		c.Stderr = &output
+		// Don't wait on children of cmd that outlive it and hold the output open
+		c.WaitDelay = time.Second
+
+		if err := c.Run(); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("command %s: %w", cmd, ctx.Err())
+			}
+			if out := commandOutput(output.String()); out != "" {
+				return fmt.Errorf("command %s: %w: %s", cmd, err, out)
+			}
+			return fmt.Errorf("command %s: %w", cmd, err)
+		}
+
+		return nil
+	}
+}
+
+// commandOutput trims command output for inclusion in an error message
+func commandOutput(out string) string {
+	out = strings.TrimSpace(out)
+	if len(out) > maxCommandOutput {
+		out = out[:maxCommandOutput] + "..."
+	}
+	return out
+}
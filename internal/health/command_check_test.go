@@ -0,0 +1,60 @@
+//go:build unix
+
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandCheck_Success(t *testing.T) {
+	check := CommandCheck("true", nil, time.Second)
+
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected check to pass, got %v", err)
+	}
+}
+
+func TestCommandCheck_NonZeroExit(t *testing.T) {
+	check := CommandCheck("false", nil, time.Second)
+
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected check to fail on a non-zero exit")
+	}
+}
+
+func TestCommandCheck_IncludesOutput(t *testing.T) {
+	check := CommandCheck("sh", []string{"-c", "echo queue backlog too high >&2; exit 3"}, time.Second)
+
+	err := check(context.Background())
+	if err == nil {
+		t.Fatal("Expected check to fail")
+	}
+	if !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "queue backlog too high") {
+		t.Errorf("Expected the exit status and output in the error, got %v", err)
+	}
+}
+
+func TestCommandCheck_MissingCommand(t *testing.T) {
+	check := CommandCheck("/nonexistent/readiness-command", nil, time.Second)
+
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected check to fail for a missing command")
+	}
+}
+
+func TestCommandCheck_Timeout(t *testing.T) {
+	check := CommandCheck("sleep", []string{"5"}, 50*time.Millisecond)
+
+	start := time.Now()
+	err := check(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the command to be killed at the timeout, took %v", elapsed)
+	}
+}
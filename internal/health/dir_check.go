@@ -0,0 +1,29 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// WritableDirCheck returns a check that creates and removes a temp file in
+// path, failing if the directory is missing or not writable
+func WritableDirCheck(path string) CheckFunc {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(path, ".readyz-*")
+		if err != nil {
+			return fmt.Errorf("data dir %s is not writable: %w", path, err)
+		}
+
+		name := f.Name()
+		if err := f.Close(); err != nil {
+			os.Remove(name)
+			return fmt.Errorf("data dir %s: close temp file: %w", path, err)
+		}
+		if err := os.Remove(name); err != nil {
+			return fmt.Errorf("data dir %s: remove temp file: %w", path, err)
+		}
+
+		return nil
+	}
+}
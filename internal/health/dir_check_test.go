@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWritableDirCheck_Writable(t *testing.T) {
+	dir := t.TempDir()
+
+	check := WritableDirCheck(dir)
+
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected check to pass, got %v", err)
+	}
+
+	// The probe file must be cleaned up
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected empty directory, found %d entries", len(entries))
+	}
+}
+
+func TestWritableDirCheck_ReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	defer os.Chmod(dir, 0o755)
+
+	check := WritableDirCheck(dir)
+
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected check to fail for a read-only directory")
+	}
+}
+
+func TestWritableDirCheck_Missing(t *testing.T) {
+	check := WritableDirCheck(filepath.Join(t.TempDir(), "missing"))
+
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected check to fail for a missing directory")
+	}
+}
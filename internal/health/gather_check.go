@@ -0,0 +1,19 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// GatherCheck returns a check that gathers every metric from gatherer,
+// failing when a collector errors so a broken /metrics is caught by readiness
+func GatherCheck(gatherer prometheus.Gatherer) CheckFunc {
+	return func(ctx context.Context) error {
+		if _, err := gatherer.Gather(); err != nil {
+			return fmt.Errorf("gather metrics: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"monitoring-dashboard-automation/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// faultyCollector reports an invalid metric on every collection
+type faultyCollector struct {
+	desc *prometheus.Desc
+}
+
+func (c *faultyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *faultyCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.NewInvalidMetric(c.desc, errors.New("collector broke"))
+}
+
+func TestGatherCheck_Passes(t *testing.T) {
+	check := GatherCheck(metrics.NewRegistry().GetRegistry())
+
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected check to pass, got %v", err)
+	}
+}
+
+func TestGatherCheck_FaultyCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&faultyCollector{desc: prometheus.NewDesc("faulty_metric", "Always fails", nil, nil)})
+
+	check := GatherCheck(registry)
+
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected check to fail when a collector errors")
+	}
+}
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPCheck returns a check that performs a GET against url and fails if the
+// request errors or the response status is >= 400. The check gives up after
+// timeout or when the context passed to it ends, whichever comes first.
+func HTTPCheck(name, url string, timeout time.Duration) CheckFunc {
+	client := &http.Client{}
+
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("%s: invalid request: %w", name, err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s: request failed: %w", name, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("%s: unexpected status %d", name, resp.StatusCode)
+		}
+
+		return nil
+	}
+}
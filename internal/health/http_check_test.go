@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPCheck_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	check := HTTPCheck("prometheus", server.URL, time.Second)
+
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected check to pass, got %v", err)
+	}
+}
+
+func TestHTTPCheck_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	check := HTTPCheck("prometheus", server.URL, time.Second)
+
+	err := check(context.Background())
+	if err == nil {
+		t.Fatal("Expected check to fail on 500 response")
+	}
+	if !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected status code in error, got %v", err)
+	}
+}
+
+func TestHTTPCheck_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	check := HTTPCheck("prometheus", server.URL, 50*time.Millisecond)
+
+	start := time.Now()
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected check to fail on timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected check to give up after its timeout, took %v", elapsed)
+	}
+}
+
+func TestHTTPCheck_HonorsContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// The caller's deadline is shorter than the check's own timeout
+	check := HTTPCheck("prometheus", server.URL, 10*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := check(ctx); err == nil {
+		t.Error("Expected check to fail when the context deadline passes")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected check to honor the context deadline, took %v", elapsed)
+	}
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultLivenessLockTimeout bounds how long a deep liveness probe waits for the lock
+const defaultLivenessLockTimeout = 500 * time.Millisecond
+
+// livenessLock is a lock shared with the deep liveness probe. Simulating a
+// deadlock parks a goroutine that holds the lock until the simulation ends.
+type livenessLock struct {
+	// sem is a one-slot semaphore so acquisition can time out
+	sem     chan struct{}
+	timeout time.Duration
+
+	mu      sync.Mutex
+	release chan struct{}
+}
+
+func newLivenessLock() *livenessLock {
+	return &livenessLock{
+		sem:     make(chan struct{}, 1),
+		timeout: defaultLivenessLockTimeout,
+	}
+}
+
+// SetSimulatedDeadlock starts or stops a stuck goroutine holding the liveness lock
+func (c *Checker) SetSimulatedDeadlock(enabled bool) {
+	l := c.liveness
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if enabled == (l.release != nil) {
+		return
+	}
+
+	if !enabled {
+		close(l.release)
+		l.release = nil
+		return
+	}
+
+	release := make(chan struct{})
+	acquired := make(chan struct{})
+	go func() {
+		l.sem <- struct{}{}
+		close(acquired)
+		<-release
+		<-l.sem
+	}()
+	// Wait until the lock is held so probes fail as soon as this returns
+	<-acquired
+	l.release = release
+}
+
+// IsSimulatedDeadlock returns whether a deadlock is being simulated
+func (c *Checker) IsSimulatedDeadlock() bool {
+	l := c.liveness
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.release != nil
+}
+
+// CheckLiveness tries to acquire the liveness lock and fails if it cannot do
+// so before the lock timeout, which is how a deadlocked process looks from outside
+func (c *Checker) CheckLiveness(ctx context.Context) error {
+	l := c.liveness
+
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	select {
+	case l.sem <- struct{}{}:
+		<-l.sem
+		return nil
+	case <-ctx.Done():
+		return &HealthCheckError{
+			Component: "deadlock",
+			Message:   "timed out acquiring liveness lock",
+		}
+	}
+}
+
+// DeepLivenessHandler runs the liveness lock probe and returns 503 when it fails
+func DeepLivenessHandler(checker *Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := checker.CheckLiveness(r.Context()); err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Not Live: " + err.Error()))
+			return
+		}
+
+		LivenessHandler(w, r)
+	}
+}
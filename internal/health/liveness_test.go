@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChecker_CheckLiveness_SimulatedDeadlock(t *testing.T) {
+	checker := NewChecker()
+	checker.liveness.timeout = 20 * time.Millisecond
+
+	if err := checker.CheckLiveness(context.Background()); err != nil {
+		t.Fatalf("Expected liveness to pass, got %v", err)
+	}
+
+	checker.SetSimulatedDeadlock(true)
+	if !checker.IsSimulatedDeadlock() {
+		t.Error("Expected deadlock simulation to be enabled")
+	}
+
+	err := checker.CheckLiveness(context.Background())
+	if err == nil {
+		t.Fatal("Expected liveness to fail while deadlocked")
+	}
+
+	healthErr, ok := err.(*HealthCheckError)
+	if !ok || healthErr.Component != "deadlock" {
+		t.Errorf("Expected deadlock HealthCheckError, got %v", err)
+	}
+
+	checker.SetSimulatedDeadlock(false)
+	if checker.IsSimulatedDeadlock() {
+		t.Error("Expected deadlock simulation to be disabled")
+	}
+
+	if err := checker.CheckLiveness(context.Background()); err != nil {
+		t.Errorf("Expected liveness to recover, got %v", err)
+	}
+}
+
+func TestChecker_SetSimulatedDeadlock_Idempotent(t *testing.T) {
+	checker := NewChecker()
+	checker.liveness.timeout = 20 * time.Millisecond
+
+	checker.SetSimulatedDeadlock(true)
+	checker.SetSimulatedDeadlock(true)
+	checker.SetSimulatedDeadlock(false)
+	checker.SetSimulatedDeadlock(false)
+
+	if err := checker.CheckLiveness(context.Background()); err != nil {
+		t.Errorf("Expected liveness to pass after repeated toggles, got %v", err)
+	}
+}
+
+func TestDeepLivenessHandler(t *testing.T) {
+	checker := NewChecker()
+	checker.liveness.timeout = 20 * time.Millisecond
+	handler := DeepLivenessHandler(checker)
+
+	checker.SetSimulatedDeadlock(true)
+
+	req := httptest.NewRequest("GET", "/healthz?deep=true", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d while deadlocked, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	checker.SetSimulatedDeadlock(false)
+
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d after disabling, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "OK" {
+		t.Errorf("Expected body 'OK', got '%s'", w.Body.String())
+	}
+}
@@ -3,35 +3,140 @@ package health
 import (
 	"context"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"monitoring-dashboard-automation/internal/httpjson"
 )
 
 // CheckFunc represents a health check function
 type CheckFunc func(ctx context.Context) error
 
+// Severity describes how much a failing check affects readiness
+type Severity int
+
+const (
+	// SeverityInfo failures are reported but do not change readiness
+	SeverityInfo Severity = iota
+	// SeverityWarn failures keep the app ready but mark it degraded
+	SeverityWarn
+	// SeverityCritical failures make the app not ready (503)
+	SeverityCritical
+)
+
+// String returns the level name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	default:
+		return "critical"
+	}
+}
+
+// Readiness statuses reported by Evaluate
+const (
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"
+	StatusNotReady = "not_ready"
+)
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Severity Severity `json:"severity"`
+	Level    string   `json:"level"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Report summarizes all readiness checks and the overall outcome
+type Report struct {
+	Status   string        `json:"status"`
+	Severity Severity      `json:"severity"`
+	Level    string        `json:"level"`
+	Checks   []CheckResult `json:"checks"`
+	
+	// Latencies maps each check name to how long it took in milliseconds,
+	// so slow dependencies show up even while every check passes
+	Latencies map[string]float64 `json:"latencies"`
+	
+	// err is the first critical failure, if any
+	err error
+}
+
+// Err returns the first critical failure, or nil when the app is ready or degraded
+func (r *Report) Err() error {
+	return r.err
+}
+
 // Checker manages health checks for the application
 type Checker struct {
-	checks map[string]CheckFunc
-	mu     sync.RWMutex
+	checks     map[string]CheckFunc
+	severities map[string]Severity
+	mu         sync.RWMutex
 	
 	// Toggle for testing - allows forcing readiness to fail
 	forceFailure bool
 	failureMu    sync.RWMutex
+	
+	// Set while the server drains for shutdown so load balancers stop routing
+	draining bool
+	
+	// Set once initialization completes, reported by the startup probe
+	started bool
+	
+	// Lock probed by deep liveness checks, used to simulate deadlocks
+	liveness *livenessLock
+	
+	// Called with the number of registered checks whenever it changes
+	onCountChange func(count int)
+	
+	// Called with every readiness report Evaluate produces
+	onReport func(report *Report)
+	
+	// When the current readiness outage started (zero while ready), and the
+	// callback told how long each outage lasted once readiness recovers
+	outageMu    sync.Mutex
+	outageStart time.Time
+	onRecovery  func(outage time.Duration)
+	
+	// How long a check run's report is reused (zero disables caching), the
+	// cached report and when it was produced; cacheMu is held while checks
+	// run so concurrent probes share a single run
+	cacheTTL time.Duration
+	cacheMu  sync.Mutex
+	cached   *Report
+	cachedAt time.Time
+	now      func() time.Time
 }
 
 // NewChecker creates a new health checker
 func NewChecker() *Checker {
 	return &Checker{
-		checks: make(map[string]CheckFunc),
+		checks:     make(map[string]CheckFunc),
+		severities: make(map[string]Severity),
+		liveness:   newLivenessLock(),
+		now:        time.Now,
 	}
 }
 
-// AddCheck adds a named health check
+// AddCheck adds a named health check with critical severity
 func (c *Checker) AddCheck(name string, check CheckFunc) {
+	c.AddCheckWithSeverity(name, check, SeverityCritical)
+}
+
+// AddCheckWithSeverity adds a named health check with the given severity
+func (c *Checker) AddCheckWithSeverity(name string, check CheckFunc, severity Severity) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.checks[name] = check
+	c.severities[name] = severity
+	c.countChanged()
 }
 
 // RemoveCheck removes a named health check
@@ -39,6 +144,62 @@ func (c *Checker) RemoveCheck(name string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.checks, name)
+	delete(c.severities, name)
+	c.countChanged()
+}
+
+// OnCheckCountChange registers fn to be called with the number of registered
+// checks, immediately and after every add or remove, so leaks are visible
+func (c *Checker) OnCheckCountChange(fn func(count int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onCountChange = fn
+	c.countChanged()
+}
+
+// countChanged reports the check count to the observer; callers must hold c.mu
+func (c *Checker) countChanged() {
+	if c.onCountChange != nil {
+		c.onCountChange(len(c.checks))
+	}
+}
+
+// OnReport registers fn to be called with the report of every readiness
+// evaluation, so outcomes can be recorded as they happen
+func (c *Checker) OnReport(fn func(report *Report)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onReport = fn
+}
+
+// OnRecovery registers fn to be called with the length of each readiness
+// outage when the checker goes from not ready back to ready (or degraded)
+func (c *Checker) OnRecovery(fn func(outage time.Duration)) {
+	c.outageMu.Lock()
+	defer c.outageMu.Unlock()
+	c.onRecovery = fn
+}
+
+// trackOutage records when readiness starts failing and reports the outage
+// length once it recovers
+func (c *Checker) trackOutage(report *Report, now time.Time) {
+	c.outageMu.Lock()
+	defer c.outageMu.Unlock()
+	
+	if report.Status == StatusNotReady {
+		if c.outageStart.IsZero() {
+			c.outageStart = now
+		}
+		return
+	}
+	
+	if !c.outageStart.IsZero() {
+		outage := now.Sub(c.outageStart)
+		c.outageStart = time.Time{}
+		if c.onRecovery != nil {
+			c.onRecovery(outage)
+		}
+	}
 }
 
 // SetForceFailure allows toggling readiness check failure for testing
@@ -55,37 +216,182 @@ func (c *Checker) IsForceFailure() bool {
 	return c.forceFailure
 }
 
-// CheckReadiness runs all registered health checks
+// SetDraining marks the application as draining for shutdown (or not)
+func (c *Checker) SetDraining(draining bool) {
+	c.failureMu.Lock()
+	defer c.failureMu.Unlock()
+	c.draining = draining
+}
+
+// IsDraining returns whether the application is draining for shutdown
+func (c *Checker) IsDraining() bool {
+	c.failureMu.RLock()
+	defer c.failureMu.RUnlock()
+	return c.draining
+}
+
+// SetCacheTTL makes readiness reuse the result of a check run for ttl instead
+// of running every check on each probe; zero runs them every time. Forced
+// failure and draining are always reported immediately
+func (c *Checker) SetCacheTTL(ttl time.Duration) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.cacheTTL = ttl
+	c.cached = nil
+}
+
+// CheckReadiness runs all registered health checks and returns the first
+// critical failure. Warn and info failures do not make readiness fail.
 func (c *Checker) CheckReadiness(ctx context.Context) error {
+	return c.Evaluate(ctx).Err()
+}
+
+// Evaluate runs all registered health checks and reports each result along
+// with the overall status and severity. A report served from the cache is
+// not passed to OnReport or outage tracking again, so probes within the TTL
+// are not counted as fresh outcomes.
+func (c *Checker) Evaluate(ctx context.Context) *Report {
+	report, fresh := c.evaluate(ctx)
+	if !fresh {
+		return report
+	}
+	c.trackOutage(report, time.Now())
+
+	c.mu.RLock()
+	onReport := c.onReport
+	c.mu.RUnlock()
+	if onReport != nil {
+		onReport(report)
+	}
+
+	return report
+}
+
+// evaluate builds the readiness report for Evaluate, reporting whether it was
+// freshly determined rather than reused from the cache
+func (c *Checker) evaluate(ctx context.Context) (*Report, bool) {
 	// Check if force failure is enabled for testing
 	if c.IsForceFailure() {
-		return &HealthCheckError{
+		return failedReport(&HealthCheckError{
 			Component: "forced",
 			Message:   "readiness check forced to fail for testing",
-		}
+		}), true
+	}
+
+	// A draining server must not receive new traffic
+	if c.IsDraining() {
+		return failedReport(&HealthCheckError{
+			Component: "shutdown",
+			Message:   "server is draining for shutdown",
+		}), true
+	}
+
+	return c.cachedChecks(ctx)
+}
+
+// cachedChecks returns the last check run's report while it is younger than
+// the cache TTL, running the checks again once it expires. The boolean is
+// false when the cached report was returned.
+func (c *Checker) cachedChecks(ctx context.Context) (*Report, bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	if c.cacheTTL <= 0 {
+		return c.runChecks(ctx), true
 	}
 
+	now := c.now()
+	if c.cached != nil && now.Sub(c.cachedAt) < c.cacheTTL {
+		return c.cached, false
+	}
+
+	c.cached = c.runChecks(ctx)
+	c.cachedAt = now
+	return c.cached, true
+}
+
+// runChecks runs every registered check and builds the report from them
+func (c *Checker) runChecks(ctx context.Context) *Report {
 	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
 	checks := make(map[string]CheckFunc, len(c.checks))
+	severities := make(map[string]Severity, len(c.severities))
 	for name, check := range c.checks {
+		names = append(names, name)
 		checks[name] = check
+		severities[name] = c.severities[name]
 	}
 	c.mu.RUnlock()
+	sort.Strings(names)
 
 	// Run all checks with a timeout
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	for name, check := range checks {
-		if err := check(ctx); err != nil {
-			return &HealthCheckError{
-				Component: name,
-				Message:   err.Error(),
+	report := &Report{
+		Status:    StatusReady,
+		Checks:    make([]CheckResult, 0, len(names)),
+		Latencies: make(map[string]float64, len(names)),
+	}
+
+	for _, name := range names {
+		severity := severities[name]
+		result := CheckResult{
+			Name:     name,
+			Status:   "pass",
+			Severity: severity,
+			Level:    severity.String(),
+		}
+
+		start := time.Now()
+		err := checks[name](ctx)
+		report.Latencies[name] = float64(time.Since(start)) / float64(time.Millisecond)
+
+		if err != nil {
+			result.Status = "fail"
+			result.Error = err.Error()
+
+			if severity > report.Severity {
+				report.Severity = severity
+			}
+			if severity == SeverityCritical && report.err == nil {
+				report.err = &HealthCheckError{
+					Component: name,
+					Message:   err.Error(),
+				}
 			}
 		}
+
+		report.Checks = append(report.Checks, result)
 	}
 
-	return nil
+	switch report.Severity {
+	case SeverityCritical:
+		report.Status = StatusNotReady
+	case SeverityWarn:
+		report.Status = StatusDegraded
+	}
+	report.Level = report.Severity.String()
+
+	return report
+}
+
+// failedReport builds a not-ready report for a failure outside the registered checks
+func failedReport(err *HealthCheckError) *Report {
+	return &Report{
+		Status:   StatusNotReady,
+		Severity: SeverityCritical,
+		Level:    SeverityCritical.String(),
+		Checks: []CheckResult{{
+			Name:     err.Component,
+			Status:   "fail",
+			Severity: SeverityCritical,
+			Level:    SeverityCritical.String(),
+			Error:    err.Message,
+		}},
+		Latencies: map[string]float64{},
+		err:       err,
+	}
 }
 
 // HealthCheckError represents a health check failure
@@ -105,20 +411,34 @@ func LivenessHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-// ReadinessHandler checks readiness and returns appropriate status
+// ReadinessHandler checks readiness and returns appropriate status.
+// Clients sending "Accept: application/json" receive the full check report.
 func ReadinessHandler(checker *Checker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		
-		if err := checker.CheckReadiness(ctx); err != nil {
-			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte("Not Ready: " + err.Error()))
+		report := checker.Evaluate(ctx)
+		
+		statusCode := http.StatusOK
+		if report.Status == StatusNotReady {
+			statusCode = http.StatusServiceUnavailable
+		}
+		
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			httpjson.Write(w, r, statusCode, report)
 			return
 		}
-
+		
 		w.Header().Set("Content-Type", "text/plain")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Ready"))
+		w.WriteHeader(statusCode)
+		
+		switch report.Status {
+		case StatusNotReady:
+			w.Write([]byte("Not Ready: " + report.Err().Error()))
+		case StatusDegraded:
+			w.Write([]byte("Degraded"))
+		default:
+			w.Write([]byte("Ready"))
+		}
 	}
-}
\ No newline at end of file
+}
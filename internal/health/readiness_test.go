@@ -2,6 +2,7 @@ package health
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -142,6 +143,31 @@ func TestChecker_CheckReadiness_ForceFailure(t *testing.T) {
 	}
 }
 
+func TestChecker_CheckReadiness_Draining(t *testing.T) {
+	checker := NewChecker()
+	
+	checker.SetDraining(true)
+	if !checker.IsDraining() {
+		t.Error("Expected checker to be draining")
+	}
+	
+	err := checker.CheckReadiness(context.Background())
+	healthErr, ok := err.(*HealthCheckError)
+	if !ok {
+		t.Fatalf("Expected HealthCheckError, got %T", err)
+	}
+	
+	if healthErr.Component != "shutdown" {
+		t.Errorf("Expected component 'shutdown', got '%s'", healthErr.Component)
+	}
+	
+	// Leaving the draining state restores readiness
+	checker.SetDraining(false)
+	if err := checker.CheckReadiness(context.Background()); err != nil {
+		t.Errorf("Expected readiness after draining is cleared, got %v", err)
+	}
+}
+
 func TestChecker_CheckReadiness_Timeout(t *testing.T) {
 	checker := NewChecker()
 	
@@ -264,6 +290,128 @@ func TestReadinessHandler_ForceFailure(t *testing.T) {
 	}
 }
 
+func TestReadinessHandler_Severities(t *testing.T) {
+	failing := func(ctx context.Context) error {
+		return errors.New("dependency unavailable")
+	}
+	passing := func(ctx context.Context) error {
+		return nil
+	}
+	
+	tests := []struct {
+		name           string
+		severity       Severity
+		expectedCode   int
+		expectedStatus string
+		expectedLevel  string
+		expectedText   string
+	}{
+		{
+			name:           "info failure stays ready",
+			severity:       SeverityInfo,
+			expectedCode:   http.StatusOK,
+			expectedStatus: StatusReady,
+			expectedLevel:  "info",
+			expectedText:   "Ready",
+		},
+		{
+			name:           "warn failure is degraded",
+			severity:       SeverityWarn,
+			expectedCode:   http.StatusOK,
+			expectedStatus: StatusDegraded,
+			expectedLevel:  "warn",
+			expectedText:   "Degraded",
+		},
+		{
+			name:           "critical failure is not ready",
+			severity:       SeverityCritical,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: StatusNotReady,
+			expectedLevel:  "critical",
+			expectedText:   "Not Ready: health check failed for dependency: dependency unavailable",
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker()
+			checker.AddCheckWithSeverity("dependency", failing, tt.severity)
+			checker.AddCheck("database", passing)
+			handler := ReadinessHandler(checker)
+			
+			// JSON report
+			req := httptest.NewRequest("GET", "/readyz", nil)
+			req.Header.Set("Accept", "application/json")
+			w := httptest.NewRecorder()
+			
+			handler(w, req)
+			
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected Content-Type 'application/json', got '%s'", w.Header().Get("Content-Type"))
+			}
+			
+			var report Report
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			
+			if report.Status != tt.expectedStatus {
+				t.Errorf("Expected status '%s', got '%s'", tt.expectedStatus, report.Status)
+			}
+			if report.Severity != tt.severity || report.Level != tt.expectedLevel {
+				t.Errorf("Expected overall severity %d (%s), got %d (%s)", tt.severity, tt.expectedLevel, report.Severity, report.Level)
+			}
+			if len(report.Checks) != 2 {
+				t.Fatalf("Expected 2 check results, got %d", len(report.Checks))
+			}
+			
+			// Checks are reported in name order
+			if report.Checks[0].Name != "database" || report.Checks[0].Status != "pass" {
+				t.Errorf("Expected passing database check, got %+v", report.Checks[0])
+			}
+			if report.Checks[1].Name != "dependency" || report.Checks[1].Status != "fail" || report.Checks[1].Severity != tt.severity {
+				t.Errorf("Expected failing dependency check with severity %d, got %+v", tt.severity, report.Checks[1])
+			}
+			
+			// Plain text response
+			req = httptest.NewRequest("GET", "/readyz", nil)
+			w = httptest.NewRecorder()
+			
+			handler(w, req)
+			
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected text status %d, got %d", tt.expectedCode, w.Code)
+			}
+			if w.Body.String() != tt.expectedText {
+				t.Errorf("Expected body '%s', got '%s'", tt.expectedText, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestChecker_Evaluate_AllPassing(t *testing.T) {
+	checker := NewChecker()
+	checker.AddCheckWithSeverity("cache", func(ctx context.Context) error {
+		return nil
+	}, SeverityWarn)
+	
+	report := checker.Evaluate(context.Background())
+	
+	if report.Status != StatusReady {
+		t.Errorf("Expected status '%s', got '%s'", StatusReady, report.Status)
+	}
+	if report.Severity != SeverityInfo {
+		t.Errorf("Expected overall severity info, got %s", report.Level)
+	}
+	if report.Err() != nil {
+		t.Errorf("Expected no error, got %v", report.Err())
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 || 
@@ -278,4 +426,192 @@ func containsAt(s, substr string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+func TestReadinessHandler_Latencies(t *testing.T) {
+	checker := NewChecker()
+	checker.AddCheck("slow", func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	checker.AddCheck("fast", func(ctx context.Context) error {
+		return nil
+	})
+	handler := ReadinessHandler(checker)
+	
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	
+	handler(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	
+	if len(report.Latencies) != 2 {
+		t.Fatalf("Expected latencies for 2 checks, got %v", report.Latencies)
+	}
+	if slow := report.Latencies["slow"]; slow < 50 || slow > 1000 {
+		t.Errorf("Expected slow check latency of about 50ms, got %vms", slow)
+	}
+	if fast := report.Latencies["fast"]; fast < 0 || fast >= 50 {
+		t.Errorf("Expected fast check latency under 50ms, got %vms", fast)
+	}
+}
+
+func TestChecker_OnReport(t *testing.T) {
+	checker := NewChecker()
+	checker.AddCheck("database", func(ctx context.Context) error { return errors.New("down") })
+	
+	var reports []*Report
+	checker.OnReport(func(report *Report) {
+		reports = append(reports, report)
+	})
+	
+	checker.CheckReadiness(context.Background())
+	checker.SetForceFailure(true)
+	checker.Evaluate(context.Background())
+	
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+	if reports[0].Checks[0].Name != "database" || reports[0].Status != StatusNotReady {
+		t.Errorf("Expected the database failure to be reported, got %+v", reports[0])
+	}
+	if reports[1].Checks[0].Name != "forced" {
+		t.Errorf("Expected the forced failure to be reported, got %+v", reports[1])
+	}
+}
+
+func TestChecker_OnRecovery(t *testing.T) {
+	checker := NewChecker()
+	
+	var outages []time.Duration
+	checker.OnRecovery(func(outage time.Duration) {
+		outages = append(outages, outage)
+	})
+	
+	checker.Evaluate(context.Background())
+	
+	checker.SetForceFailure(true)
+	checker.Evaluate(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	checker.Evaluate(context.Background())
+	
+	if len(outages) != 0 {
+		t.Fatalf("Expected no outage reported while still failing, got %v", outages)
+	}
+	
+	checker.SetForceFailure(false)
+	checker.Evaluate(context.Background())
+	checker.Evaluate(context.Background())
+	
+	if len(outages) != 1 {
+		t.Fatalf("Expected exactly one outage, got %v", outages)
+	}
+	if outages[0] < 100*time.Millisecond || outages[0] > time.Second {
+		t.Errorf("Expected an outage of about 100ms, got %v", outages[0])
+	}
+}
+
+func TestChecker_OnCheckCountChange(t *testing.T) {
+	checker := NewChecker()
+	checker.AddCheck("existing", func(ctx context.Context) error { return nil })
+	
+	var counts []int
+	checker.OnCheckCountChange(func(count int) {
+		counts = append(counts, count)
+	})
+	
+	checker.AddCheck("database", func(ctx context.Context) error { return nil })
+	checker.AddCheckWithSeverity("cache", func(ctx context.Context) error { return nil }, SeverityWarn)
+	checker.RemoveCheck("database")
+	
+	expected := []int{1, 2, 3, 2}
+	if len(counts) != len(expected) {
+		t.Fatalf("Expected counts %v, got %v", expected, counts)
+	}
+	for i := range expected {
+		if counts[i] != expected[i] {
+			t.Errorf("Expected counts %v, got %v", expected, counts)
+			break
+		}
+	}
+}
+
+func TestChecker_CacheTTL(t *testing.T) {
+	checker := NewChecker()
+	now := time.Unix(1000, 0)
+	checker.now = func() time.Time { return now }
+	checker.SetCacheTTL(10 * time.Second)
+	
+	runs := 0
+	checker.AddCheck("database", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+	
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := checker.CheckReadiness(ctx); err != nil {
+			t.Fatalf("Expected ready, got %v", err)
+		}
+		now = now.Add(time.Second)
+	}
+	if runs != 1 {
+		t.Errorf("Expected checks to run once within the TTL, ran %d times", runs)
+	}
+	
+	now = now.Add(10 * time.Second)
+	checker.CheckReadiness(ctx)
+	if runs != 2 {
+		t.Errorf("Expected checks to run again after the TTL, ran %d times", runs)
+	}
+}
+
+func TestChecker_CacheTTL_CachedReportsNotObserved(t *testing.T) {
+	checker := NewChecker()
+	checker.SetCacheTTL(time.Hour)
+	checker.AddCheck("database", func(ctx context.Context) error { return errors.New("down") })
+	
+	reports := 0
+	checker.OnReport(func(report *Report) {
+		reports++
+	})
+	
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := checker.CheckReadiness(ctx); err == nil {
+			t.Fatal("Expected the cached failure to be returned")
+		}
+	}
+	if reports != 1 {
+		t.Errorf("Expected one report for the check run, got %d", reports)
+	}
+}
+
+func TestChecker_CacheTTL_ForceFailureBypassesCache(t *testing.T) {
+	checker := NewChecker()
+	checker.SetCacheTTL(time.Hour)
+	checker.AddCheck("database", func(ctx context.Context) error { return nil })
+	
+	ctx := context.Background()
+	if err := checker.CheckReadiness(ctx); err != nil {
+		t.Fatalf("Expected ready, got %v", err)
+	}
+	
+	checker.SetForceFailure(true)
+	if err := checker.CheckReadiness(ctx); err == nil {
+		t.Error("Expected forced failure despite a cached ready result")
+	}
+	
+	checker.SetForceFailure(false)
+	if err := checker.CheckReadiness(ctx); err != nil {
+		t.Errorf("Expected cached ready result after clearing forced failure, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"net/http"
+)
+
+// MarkStarted records that initialization has completed
+func (c *Checker) MarkStarted() {
+	c.failureMu.Lock()
+	defer c.failureMu.Unlock()
+	c.started = true
+}
+
+// IsStarted returns whether initialization has completed
+func (c *Checker) IsStarted() bool {
+	c.failureMu.RLock()
+	defer c.failureMu.RUnlock()
+	return c.started
+}
+
+// StartupHandler returns 200 once the app has been marked as started and
+// 503 before that - used for startup probes
+func StartupHandler(checker *Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+
+		if !checker.IsStarted() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Starting"))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Started"))
+	}
+}
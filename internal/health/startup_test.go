@@ -0,0 +1,46 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChecker_MarkStarted(t *testing.T) {
+	checker := NewChecker()
+
+	if checker.IsStarted() {
+		t.Error("Expected new checker not to be started")
+	}
+
+	checker.MarkStarted()
+
+	if !checker.IsStarted() {
+		t.Error("Expected checker to be started after MarkStarted")
+	}
+}
+
+func TestStartupHandler(t *testing.T) {
+	checker := NewChecker()
+	handler := StartupHandler(checker)
+
+	req := httptest.NewRequest("GET", "/startupz", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d before startup, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	checker.MarkStarted()
+
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d after startup, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "Started" {
+		t.Errorf("Expected body 'Started', got '%s'", w.Body.String())
+	}
+}
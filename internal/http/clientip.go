@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP returns the address of the client that sent r. Without trustProxy
+// it is the connection's address, since forwarding headers can be forged.
+// With trustProxy, X-Forwarded-For is read from the right, skipping the
+// private and loopback hops of our own proxies, and the first other address
+// is the client; when every hop is internal the left-most is used. X-Real-IP
+// is used when there is no X-Forwarded-For.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if !trustProxy {
+		return remoteIP(r)
+	}
+	
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		var hops []net.IP
+		for _, hop := range strings.Split(forwarded, ",") {
+			if ip := net.ParseIP(strings.TrimSpace(hop)); ip != nil {
+				hops = append(hops, ip)
+			}
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			if !isInternalIP(hops[i]) {
+				return hops[i].String()
+			}
+		}
+		if len(hops) > 0 {
+			return hops[0].String()
+		}
+	}
+	
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	
+	return remoteIP(r)
+}
+
+// isInternalIP reports whether ip is a private, loopback or link-local
+// address, as used by proxies inside our own network
+func isInternalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
+
+// remoteIP returns the host of the connection's RemoteAddr
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
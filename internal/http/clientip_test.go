@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		trustProxy   bool
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		expected     string
+	}{
+		{name: "direct connection", remoteAddr: "203.0.113.7:4000", expected: "203.0.113.7"},
+		{name: "direct IPv6 connection", remoteAddr: "[2001:db8::1]:4000", expected: "2001:db8::1"},
+		{name: "forwarding headers ignored without trust", remoteAddr: "10.0.0.9:4000", forwardedFor: "198.51.100.1", realIP: "198.51.100.2", expected: "10.0.0.9"},
+		{name: "single forwarded client", trustProxy: true, remoteAddr: "10.0.0.9:4000", forwardedFor: "198.51.100.1", expected: "198.51.100.1"},
+		{name: "internal proxy hops skipped", trustProxy: true, remoteAddr: "10.0.0.9:4000", forwardedFor: "198.51.100.1, 10.0.0.5, 127.0.0.1", expected: "198.51.100.1"},
+		{name: "forged left-most entry ignored", trustProxy: true, remoteAddr: "10.0.0.9:4000", forwardedFor: "192.0.2.66, 198.51.100.1, 10.0.0.5", expected: "198.51.100.1"},
+		{name: "internal client behind proxies", trustProxy: true, remoteAddr: "10.0.0.9:4000", forwardedFor: "10.1.2.3, 192.168.1.10", expected: "10.1.2.3"},
+		{name: "malformed hops skipped", trustProxy: true, remoteAddr: "10.0.0.9:4000", forwardedFor: "unknown, 198.51.100.1", expected: "198.51.100.1"},
+		{name: "X-Real-IP without X-Forwarded-For", trustProxy: true, remoteAddr: "10.0.0.9:4000", realIP: "198.51.100.2", expected: "198.51.100.2"},
+		{name: "no forwarding headers behind proxy", trustProxy: true, remoteAddr: "10.0.0.9:4000", expected: "10.0.0.9"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			
+			if ip := clientIP(req, tt.trustProxy); ip != tt.expected {
+				t.Errorf("Expected client IP %s, got %s", tt.expected, ip)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+
+	"monitoring-dashboard-automation/internal/metrics"
+)
+
+// ConcurrencyLimitMiddleware serves at most limit requests at a time and
+// sheds the rest with 503 and Retry-After instead of queueing them
+func ConcurrencyLimitMiddleware(metricsRegistry *metrics.Registry, limit int) func(next http.Handler) http.Handler {
+	slots := make(chan struct{}, limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				metricsRegistry.IncConcurrencyRejected(getRoutePattern(r))
+
+				w.Header().Set("Retry-After", "1")
+				writeJSONError(w, r, http.StatusServiceUnavailable, "Too many concurrent requests")
+				return
+			}
+			// Released even when the handler panics, so a panic cannot leak a slot
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"monitoring-dashboard-automation/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+func TestConcurrencyLimitMiddleware_Saturated(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := ConcurrencyLimitMiddleware(metricsRegistry, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("block") == "true" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	// Fill both slots with requests that block until released
+	var wg sync.WaitGroup
+	blockedCodes := make([]int, 2)
+	for i := range blockedCodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/work?block=true", nil))
+			blockedCodes[i] = w.Code
+		}(i)
+		<-started
+	}
+	
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+		
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d while saturated, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1" {
+			t.Errorf("Expected Retry-After 1, got %q", retryAfter)
+		}
+	}
+	if rejected := findCounter(t, metricsRegistry, "concurrency_rejected_total"); rejected != 3 {
+		t.Errorf("Expected concurrency_rejected_total 3, got %v", rejected)
+	}
+	
+	// Once the in-flight requests complete, new requests are served again
+	close(release)
+	wg.Wait()
+	for i, code := range blockedCodes {
+		if code != http.StatusOK {
+			t.Errorf("Expected in-flight request %d to complete with %d, got %d", i, http.StatusOK, code)
+		}
+	}
+	
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+		
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d after recovery, got %d", http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestConcurrencyLimitMiddleware_ReleasesOnPanic(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	handler := PanicRecoveryMiddleware(zap.NewNop(), metricsRegistry)(
+		ConcurrencyLimitMiddleware(metricsRegistry, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("panic") == "true" {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusOK)
+		})),
+	)
+	
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/panic?panic=true", nil))
+		
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d after a panic, got %d", http.StatusInternalServerError, w.Code)
+		}
+	}
+	
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the slot to be released after panics, got status %d", w.Code)
+	}
+}
@@ -1,17 +1,31 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"math/rand"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"monitoring-dashboard-automation/internal/health"
+	"monitoring-dashboard-automation/internal/httpjson"
+	"monitoring-dashboard-automation/internal/logging"
 	"monitoring-dashboard-automation/internal/metrics"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
 )
 
 // HealthHandlers contains all health-related HTTP handlers
@@ -26,8 +40,13 @@ func NewHealthHandlers(checker *health.Checker) *HealthHandlers {
 	}
 }
 
-// Liveness handles GET /healthz - always returns 200 OK
+// Liveness handles GET /healthz - returns 200 OK, or probes for a deadlock
+// when called with ?deep=true
 func (h *HealthHandlers) Liveness(w http.ResponseWriter, r *http.Request) {
+	if deep, _ := strconv.ParseBool(r.URL.Query().Get("deep")); deep {
+		health.DeepLivenessHandler(h.checker)(w, r)
+		return
+	}
 	health.LivenessHandler(w, r)
 }
 
@@ -37,6 +56,11 @@ func (h *HealthHandlers) Readiness(w http.ResponseWriter, r *http.Request) {
 	handler(w, r)
 }
 
+// Startup handles GET /startupz - 200 once initialization has completed
+func (h *HealthHandlers) Startup(w http.ResponseWriter, r *http.Request) {
+	health.StartupHandler(h.checker)(w, r)
+}
+
 // ToggleReadiness handles POST /api/v1/toggles/readiness - for testing
 func (h *HealthHandlers) ToggleReadiness(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -44,7 +68,7 @@ func (h *HealthHandlers) ToggleReadiness(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
@@ -52,49 +76,224 @@ func (h *HealthHandlers) ToggleReadiness(w http.ResponseWriter, r *http.Request)
 
 	response := map[string]interface{}{
 		"force_failure": req.ForceFailure,
-		"message":      "Readiness check toggle updated",
+		"message":       "Readiness check toggle updated",
+		"request_id":    requestIDFromContext(r.Context()),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// ToggleDeadlock handles POST /api/v1/toggles/deadlock - simulates a deadlock
+// that makes deep liveness probes fail
+func (h *HealthHandlers) ToggleDeadlock(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	h.checker.SetSimulatedDeadlock(req.Enabled)
+
+	response := map[string]interface{}{
+		"enabled":    req.Enabled,
+		"message":    "Deadlock simulation toggle updated",
+		"request_id": requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// WorkConfig holds the defaults the Work handler uses when a request omits parameters
+type WorkConfig struct {
+	DefaultMs     int
+	DefaultJitter int
 }
 
 // APIHandlers contains all API-related HTTP handlers
 type APIHandlers struct {
 	logger  *zap.Logger
 	metrics *metrics.Registry
+	work    WorkConfig
 }
 
 // NewAPIHandlers creates new API handlers
-func NewAPIHandlers(logger *zap.Logger, metrics *metrics.Registry) *APIHandlers {
+func NewAPIHandlers(logger *zap.Logger, metrics *metrics.Registry, work WorkConfig) *APIHandlers {
 	return &APIHandlers{
 		logger:  logger,
 		metrics: metrics,
+		work:    work,
 	}
 }
 
-// Ping handles GET /api/v1/ping - simple ping endpoint
+// Ping handles GET /api/v1/ping - simple ping endpoint, answering a bare
+// "pong" to clients that prefer text/plain over JSON in their Accept header
 func (h *APIHandlers) Ping(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+	if prefersPlainText(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "pong")
+		return
+	}
+
 	response := map[string]interface{}{
-		"message":   "pong",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"message":    "pong",
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+		"request_id": requestIDFromContext(r.Context()),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// prefersPlainText reports whether an Accept header ranks text/plain above
+// application/json; JSON wins ties and an empty header
+func prefersPlainText(accept string) bool {
+	var textQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		matchesText := mediaType == "text/plain" || mediaType == "text/*" || mediaType == "*/*"
+		matchesJSON := mediaType == "application/json" || mediaType == "application/*" || mediaType == "*/*"
+		if matchesText && q > textQ {
+			textQ = q
+		}
+		if matchesJSON && q > jsonQ {
+			jsonQ = q
+		}
+	}
+	return textQ > jsonQ
+}
+
+// echoResponse is the request metadata reflected by Echo
+type echoResponse struct {
+	Method     string              `json:"method" yaml:"method"`
+	Path       string              `json:"path" yaml:"path"`
+	Query      map[string][]string `json:"query" yaml:"query"`
+	Headers    map[string][]string `json:"headers" yaml:"headers"`
+	RemoteAddr string              `json:"remote_addr" yaml:"remote_addr"`
+	RequestID  string              `json:"request_id" yaml:"request_id"`
+}
+
+// Echo handles GET /api/v1/echo - reflects request metadata back to the client,
+// serialized as json (default), text or yaml depending on ?format=
+func (h *APIHandlers) Echo(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "text" && format != "yaml" {
+		writeJSONError(w, r, http.StatusBadRequest, "format must be one of json, text, yaml")
+		return
+	}
+
+	// Never reflect credentials
+	headers := r.Header.Clone()
+	headers.Del("Authorization")
+	headers.Del("Cookie")
+
+	requestID := requestIDFromContext(r.Context())
+	response := echoResponse{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Headers:    headers,
+		RemoteAddr: r.RemoteAddr,
+		RequestID:  requestID,
+	}
+
+	switch format {
+	case "yaml":
+		body, err := yaml.Marshal(response)
+		if err != nil {
+			h.logger.Error("Failed to encode YAML response", zap.Error(err))
+			writeJSONError(w, r, http.StatusInternalServerError, "Failed to encode response")
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(response.text()))
+	default:
+		httpjson.Write(w, r, http.StatusOK, response)
+	}
 }
 
-// Work handles GET /api/v1/work - simulates work with configurable duration and jitter
+// text renders the echo response as sorted "key: value" lines
+func (e echoResponse) text() string {
+	var b strings.Builder
+	b.WriteString("method: " + e.Method + "\n")
+	b.WriteString("path: " + e.Path + "\n")
+	b.WriteString("remote_addr: " + e.RemoteAddr + "\n")
+	b.WriteString("request_id: " + e.RequestID + "\n")
+	writeTextValues(&b, "query.", e.Query)
+	writeTextValues(&b, "header.", e.Headers)
+	return b.String()
+}
+
+// writeTextValues writes one "prefix+key: value" line per value, sorted by key
+func writeTextValues(b *strings.Builder, prefix string, values map[string][]string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range values[key] {
+			b.WriteString(prefix + key + ": " + value + "\n")
+		}
+	}
+}
+
+// workQueryParams lists the query parameters understood by Work
+var workQueryParams = []string{"ms", "jitter", "status", "truncate_after", "mode", "panic_rate", "fail", "fail_status", "stream", "pretty"}
+
+// maxResponseTemplateBytes bounds the POST /api/v1/work request body
+const maxResponseTemplateBytes = 4 * 1024
+
+// Work handles GET /api/v1/work - simulates work with configurable duration and jitter.
+// mode=sleep (default) waits out the duration, mode=cpu busy-loops for it to
+// generate real CPU load; the response reports the mode used. panic_rate
+// (0.0-1.0, default 0) panics for that fraction of requests to exercise
+// panic recovery. fail (0.0-1.0, default 0) makes that fraction of requests
+// respond with fail_status (default 500) once the work is done, independent
+// of the error toggle. POST accepts a JSON body whose response_template object is
+// echoed back in the response, for contract-testing demos. stream=true reports
+// progress as Server-Sent Events and sends the response as a final done event.
 func (h *APIHandlers) Work(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	msParam := r.URL.Query().Get("ms")
 	jitterParam := r.URL.Query().Get("jitter")
+	statusParam := r.URL.Query().Get("status")
+	truncateParam := r.URL.Query().Get("truncate_after")
+	mode := r.URL.Query().Get("mode")
+	panicRateParam := r.URL.Query().Get("panic_rate")
+	failParam := r.URL.Query().Get("fail")
+	failStatusParam := r.URL.Query().Get("fail_status")
+	streamParam := r.URL.Query().Get("stream")
 
 	// Default values
-	baseDuration := 100 * time.Millisecond
-	jitterDuration := time.Duration(0)
+	baseDuration := time.Duration(h.work.DefaultMs) * time.Millisecond
+	jitterDuration := time.Duration(h.work.DefaultJitter) * time.Millisecond
+	successStatus := http.StatusOK
+	truncateAfter := -1
 
 	// Parse ms parameter
 	if msParam != "" {
@@ -110,45 +309,470 @@ func (h *APIHandlers) Work(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse status parameter - only 2xx codes are valid for a successful response
+	if statusParam != "" {
+		status, err := strconv.Atoi(statusParam)
+		if err != nil || status < 200 || status > 299 {
+			writeJSONError(w, r, http.StatusBadRequest, "Status must be a 2xx status code")
+			return
+		}
+		successStatus = status
+	}
+
+	// Parse truncate_after parameter - number of body bytes sent before the connection is closed
+	if truncateParam != "" {
+		n, err := strconv.Atoi(truncateParam)
+		if err != nil || n < 0 {
+			writeJSONError(w, r, http.StatusBadRequest, "truncate_after must be a non-negative integer")
+			return
+		}
+		truncateAfter = n
+	}
+
+	// Parse mode parameter - how the work is simulated
+	if mode == "" {
+		mode = "sleep"
+	}
+	if mode != "sleep" && mode != "cpu" {
+		writeJSONError(w, r, http.StatusBadRequest, "mode must be one of sleep, cpu")
+		return
+	}
+
+	// Parse panic_rate parameter - fraction of requests that panic
+	panicRate := 0.0
+	if panicRateParam != "" {
+		rate, err := strconv.ParseFloat(panicRateParam, 64)
+		if err != nil || rate < 0.0 || rate > 1.0 {
+			writeJSONError(w, r, http.StatusBadRequest, "panic_rate must be between 0.0 and 1.0")
+			return
+		}
+		panicRate = rate
+	}
+	// Parse the response template from a POST body
+	var responseTemplate json.RawMessage
+	if r.Method == http.MethodPost {
+		template, status, err := readResponseTemplate(r)
+		if err != nil {
+			writeJSONError(w, r, status, err.Error())
+			return
+		}
+		responseTemplate = template
+	}
+
+	// Parse fail parameter - fraction of requests that fail after the work
+	failRate := 0.0
+	if failParam != "" {
+		rate, err := strconv.ParseFloat(failParam, 64)
+		if err != nil || rate < 0.0 || rate > 1.0 {
+			writeJSONError(w, r, http.StatusBadRequest, "fail must be between 0.0 and 1.0")
+			return
+		}
+		failRate = rate
+	}
+
+	// Parse fail_status parameter - the status failed requests respond with
+	failStatus := http.StatusInternalServerError
+	if failStatusParam != "" {
+		status, err := strconv.Atoi(failStatusParam)
+		if err != nil || status < 400 || status > 599 {
+			writeJSONError(w, r, http.StatusBadRequest, "fail_status must be a 4xx or 5xx status code")
+			return
+		}
+		failStatus = status
+	}
+
+	// Parse stream parameter - a streamed response is always 200 and complete
+	stream := false
+	if streamParam != "" {
+		var err error
+		stream, err = strconv.ParseBool(streamParam)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, "stream must be true or false")
+			return
+		}
+	}
+	if stream && (statusParam != "" || truncateAfter >= 0) {
+		writeJSONError(w, r, http.StatusBadRequest, "stream cannot be combined with status or truncate_after")
+		return
+	}
+
+	if panicRate > 0 && rand.Float64() < panicRate {
+		panic("injected panic from /api/v1/work")
+	}
+
 	// Calculate total duration with jitter
 	totalDuration := baseDuration
 	if jitterDuration > 0 {
 		// Add random jitter between 0 and jitterDuration
 		jitter := time.Duration(rand.Int63n(int64(jitterDuration)))
 		totalDuration += jitter
+		h.metrics.ObserveWorkJitter(jitter)
 	}
 
-	// Increment inflight jobs metric
+	h.logger.Debug("Starting work",
+		zap.Duration("duration", totalDuration),
+		zap.String("mode", mode))
+
+	// Record how busy we already are, then increment inflight jobs metric
+	h.metrics.ObserveWorkJobsInflightSnapshot()
 	h.metrics.IncWorkJobsInflight()
 	defer h.metrics.DecWorkJobsInflight()
 
 	// Simulate work with context cancellation support
 	startTime := time.Now()
-	if err := h.simulateWork(r.Context(), totalDuration); err != nil {
+	simulate := h.simulateWork
+	if mode == "cpu" {
+		simulate = h.simulateCPUWork
+	}
+
+	// Report progress while streaming; errors from here on are sent as an
+	// error event since the 200 status is already on its way
+	var events *sseWriter
+	writeError := func(statusCode int, message string) {
+		if events != nil {
+			events.send("error", errorResponse{Error: message, Status: statusCode, RequestID: requestIDFromContext(r.Context())})
+			return
+		}
+		writeJSONError(w, r, statusCode, message)
+	}
+	if stream {
+		var ok bool
+		if events, ok = newSSEWriter(w); !ok {
+			writeJSONError(w, r, http.StatusInternalServerError, "Streaming not supported")
+			return
+		}
+		simulate = withProgress(simulate, events)
+	}
+
+	if err := simulate(r.Context(), totalDuration); err != nil {
 		// Work was cancelled or failed
 		h.metrics.IncWorkFailures("simulate_work")
+		h.metrics.IncWorkCancelled()
 		h.logger.Warn("Work simulation failed", 
 			zap.Error(err),
 			zap.Duration("requested_duration", totalDuration),
 			zap.Duration("actual_duration", time.Since(startTime)))
 		
-		http.Error(w, "Work simulation cancelled", http.StatusRequestTimeout)
+		writeError(http.StatusRequestTimeout, "Work simulation cancelled")
+		return
+	}
+
+	// Fail this request if asked to, after the work so durations stay realistic
+	if failRate > 0 && rand.Float64() < failRate {
+		h.metrics.IncWorkFailures("injected_failure")
+		writeError(failStatus, "Injected work failure")
 		return
 	}
 
 	actualDuration := time.Since(startTime)
+	h.metrics.IncWorkCompleted()
+	h.metrics.ObserveWorkDuration(mode, actualDuration)
 
 	response := map[string]interface{}{
-		"message":           "work completed",
-		"requested_ms":      int(baseDuration.Milliseconds()),
-		"jitter_ms":         int(jitterDuration.Milliseconds()),
+		"message":            "work completed",
+		"requested_ms":       int(baseDuration.Milliseconds()),
+		"jitter_ms":          int(jitterDuration.Milliseconds()),
 		"actual_duration_ms": int(actualDuration.Milliseconds()),
-		"timestamp":         time.Now().UTC().Format(time.RFC3339),
+		"mode":               mode,
+		"timestamp":          time.Now().UTC().Format(time.RFC3339),
+		"injection":          injectionFromContext(r.Context()),
+		"request_id":         requestIDFromContext(r.Context()),
+	}
+	if responseTemplate != nil {
+		response["response_template"] = responseTemplate
+	}
+
+	if events != nil {
+		if err := events.send("done", response); err != nil {
+			h.metrics.IncJSONEncodeError("/api/v1/work")
+		}
+		return
+	}
+
+	if truncateAfter >= 0 {
+		writeTruncated(w, r, successStatus, response, truncateAfter)
+		return
+	}
+
+	httpjson.Write(w, r, successStatus, response)
+}
+
+// workProgressInterval is how often a streamed work request reports progress
+const workProgressInterval = 100 * time.Millisecond
+
+// workProgress is the data of a streamed work progress event
+type workProgress struct {
+	ElapsedMs int64 `json:"elapsed_ms"`
+	Percent   int   `json:"percent"`
+}
+
+// withProgress wraps simulate to send a progress event every
+// workProgressInterval while it runs. Send failures are ignored: a client
+// that went away cancels the request context, which ends the simulation.
+func withProgress(simulate func(ctx context.Context, duration time.Duration) error, events *sseWriter) func(ctx context.Context, duration time.Duration) error {
+	return func(ctx context.Context, duration time.Duration) error {
+		done := make(chan error, 1)
+		start := time.Now()
+		go func() {
+			done <- simulate(ctx, duration)
+		}()
+
+		ticker := time.NewTicker(workProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case err := <-done:
+				return err
+			case <-ticker.C:
+				elapsed := time.Since(start)
+				// 100 is left for the done event
+				percent := 99
+				if elapsed < duration {
+					percent = int(elapsed * 100 / duration)
+				}
+				events.send("progress", workProgress{ElapsedMs: elapsed.Milliseconds(), Percent: percent})
+			}
+		}
+	}
+}
+
+// Limits on POST /api/v1/work/batch, so one call cannot exhaust the process
+const (
+	maxBatchCount           = 1000
+	maxBatchConcurrency     = 100
+	defaultBatchConcurrency = 10
+)
+
+// batchWorkRequest is the body of POST /api/v1/work/batch. ms and jitter
+// fall back to the configured work defaults when omitted.
+type batchWorkRequest struct {
+	Count       int  `json:"count"`
+	Ms          *int `json:"ms"`
+	Jitter      *int `json:"jitter"`
+	Concurrency int  `json:"concurrency"`
+}
+
+// BatchWork handles POST /api/v1/work/batch - runs count simulated work jobs
+// with at most concurrency of them in flight, and reports aggregate timings.
+// Jobs not yet started are skipped once the request is cancelled.
+func (h *APIHandlers) BatchWork(w http.ResponseWriter, r *http.Request) {
+	var req batchWorkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if req.Count < 1 || req.Count > maxBatchCount {
+		writeJSONError(w, r, http.StatusBadRequest, "count must be between 1 and "+strconv.Itoa(maxBatchCount))
+		return
+	}
+	if req.Concurrency == 0 {
+		req.Concurrency = defaultBatchConcurrency
+		if req.Count < req.Concurrency {
+			req.Concurrency = req.Count
+		}
+	}
+	if req.Concurrency < 1 || req.Concurrency > maxBatchConcurrency {
+		writeJSONError(w, r, http.StatusBadRequest, "concurrency must be between 1 and "+strconv.Itoa(maxBatchConcurrency))
+		return
+	}
+
+	baseDuration := time.Duration(h.work.DefaultMs) * time.Millisecond
+	if req.Ms != nil {
+		baseDuration = time.Duration(*req.Ms) * time.Millisecond
+	}
+	jitterDuration := time.Duration(h.work.DefaultJitter) * time.Millisecond
+	if req.Jitter != nil {
+		jitterDuration = time.Duration(*req.Jitter) * time.Millisecond
+	}
+	if baseDuration < 0 || jitterDuration < 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "ms and jitter must be non-negative")
+		return
+	}
+
+	ctx := r.Context()
+	slots := make(chan struct{}, req.Concurrency)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		durations []time.Duration
+	)
+
+	start := time.Now()
+	for i := 0; i < req.Count && ctx.Err() == nil; i++ {
+		// Wait for a free slot, or stop starting jobs once cancelled
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		duration := baseDuration
+		if jitterDuration > 0 {
+			jitter := time.Duration(rand.Int63n(int64(jitterDuration)))
+			duration += jitter
+			h.metrics.ObserveWorkJitter(jitter)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			h.metrics.ObserveWorkJobsInflightSnapshot()
+			h.metrics.IncWorkJobsInflight()
+			defer h.metrics.DecWorkJobsInflight()
+
+			jobStart := time.Now()
+			if err := h.simulateWork(ctx, duration); err != nil {
+				h.metrics.IncWorkFailures("simulate_work")
+				h.metrics.IncWorkCancelled()
+				return
+			}
+
+			elapsed := time.Since(jobStart)
+			h.metrics.IncWorkCompleted()
+			h.metrics.ObserveWorkDuration("sleep", elapsed)
+
+			mu.Lock()
+			durations = append(durations, elapsed)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	wallDuration := time.Since(start)
+
+	if ctx.Err() != nil {
+		h.logger.Warn("Batch work cancelled",
+			zap.Int("count", req.Count),
+			zap.Int("completed", len(durations)),
+			zap.Duration("wall_duration", wallDuration))
+
+		writeJSONError(w, r, http.StatusRequestTimeout, "Batch work cancelled")
+		return
+	}
+
+	var total, fastest, slowest time.Duration
+	for i, d := range durations {
+		total += d
+		if i == 0 || d < fastest {
+			fastest = d
+		}
+		if d > slowest {
+			slowest = d
+		}
+	}
+
+	response := map[string]interface{}{
+		"message":          "batch completed",
+		"count":            req.Count,
+		"concurrency":      req.Concurrency,
+		"requested_ms":     int(baseDuration.Milliseconds()),
+		"jitter_ms":        int(jitterDuration.Milliseconds()),
+		"completed":        len(durations),
+		"wall_duration_ms": int(wallDuration.Milliseconds()),
+		"min_ms":           int(fastest.Milliseconds()),
+		"max_ms":           int(slowest.Milliseconds()),
+		"avg_ms":           int((total / time.Duration(len(durations))).Milliseconds()),
+		"request_id":       requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// readResponseTemplate reads the response_template object from a POST
+// /api/v1/work body. On failure it returns the status code to respond with.
+func readResponseTemplate(r *http.Request) (json.RawMessage, int, error) {
+	// Read one byte past the limit to detect oversized bodies
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxResponseTemplateBytes+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("Failed to read request body")
+	}
+	if len(body) > maxResponseTemplateBytes {
+		return nil, http.StatusRequestEntityTooLarge, errors.New("Request body must be at most " + strconv.Itoa(maxResponseTemplateBytes) + " bytes")
+	}
+
+	var req struct {
+		ResponseTemplate json.RawMessage `json:"response_template"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, http.StatusBadRequest, errors.New("Invalid JSON")
+	}
+	if len(req.ResponseTemplate) == 0 || req.ResponseTemplate[0] != '{' {
+		return nil, http.StatusBadRequest, errors.New("response_template must be a JSON object")
+	}
+
+	return req.ResponseTemplate, 0, nil
+}
+
+// Reset handles GET /api/v1/reset - hijacks the connection and closes it
+// without writing a response. Lingering is disabled so the kernel sends a
+// TCP RST instead of a graceful FIN, simulating an abrupt disconnect.
+func (h *APIHandlers) Reset(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeJSONError(w, r, http.StatusInternalServerError, "Connection reset not supported")
+		return
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		h.logger.Error("Failed to hijack connection", zap.Error(err))
+		return
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+
+	h.logger.Info("Connection reset", zap.String("remote_addr", r.RemoteAddr))
+}
+
+// Panic handles GET /api/v1/panic - panics deliberately so panic recovery,
+// stack-trace logging and 500 alerting can be verified end to end
+func (h *APIHandlers) Panic(w http.ResponseWriter, r *http.Request) {
+	panic("deliberate panic from /api/v1/panic")
+}
+
+// writeTruncated advertises the full JSON body length but sends only the first
+// n bytes, then closes the connection so clients see a truncated response
+func writeTruncated(w http.ResponseWriter, r *http.Request, statusCode int, response interface{}, n int) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		writeJSONError(w, r, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	if n > len(body) {
+		n = len(body)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusCode)
+	w.Write(body[:n])
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	// Drop the connection without sending the rest of the body
+	if hijacker, ok := w.(http.Hijacker); ok {
+		if conn, bufrw, err := hijacker.Hijack(); err == nil {
+			bufrw.Flush()
+			conn.Close()
+		}
+	}
+}
+
+// injectionFromContext returns what the injection middleware did to the
+// request, or an empty record when no injection middleware ran
+func injectionFromContext(ctx context.Context) InjectionInfo {
+	if info, ok := ctx.Value(InjectionKey).(*InjectionInfo); ok {
+		return *info
+	}
+	return InjectionInfo{}
 }
 
 // simulateWork simulates work for the given duration, respecting context cancellation
@@ -161,69 +785,537 @@ func (h *APIHandlers) simulateWork(ctx context.Context, duration time.Duration)
 	}
 }
 
+// simulateCPUWork busy-loops for the given duration, checking for context
+// cancellation between batches of computation
+func (h *APIHandlers) simulateCPUWork(ctx context.Context, duration time.Duration) error {
+	deadline := time.Now().Add(duration)
+	x := uint64(1)
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// A batch of xorshift steps, short enough to stay responsive to cancellation
+		for i := 0; i < 10000; i++ {
+			x ^= x << 13
+			x ^= x >> 7
+			x ^= x << 17
+		}
+	}
+	cpuWorkSink = x
+	return nil
+}
+
+// cpuWorkSink keeps the busy-loop result live so the computation isn't optimized away
+var cpuWorkSink uint64
+
 // ToggleHandlers contains all toggle-related HTTP handlers
 type ToggleHandlers struct {
 	logger      *zap.Logger
 	errorToggle interface {
-		SetConfig(enabled bool, rate float64, statusCode int)
+		SetConfig(enabled bool, rate float64, statusCode int, routes ...string)
 		GetConfig() (bool, float64, int)
+		GetRoutes() []string
+	}
+	latencyToggle interface {
+		SetConfig(enabled bool, minMs, maxMs int)
+		GetConfig() (bool, int, int)
+		StartRamp(startMs, endMs int, duration, interval time.Duration)
+	}
+	memoryToggle interface {
+		SetConfig(enabled bool, megabytes int)
+		GetConfig() (bool, int)
 	}
+	
+	// Route patterns error injection may be scoped to
+	knownRoutes map[string]bool
 }
 
 // NewToggleHandlers creates new toggle handlers
 func NewToggleHandlers(logger *zap.Logger, errorToggle interface {
-	SetConfig(enabled bool, rate float64, statusCode int)
+	SetConfig(enabled bool, rate float64, statusCode int, routes ...string)
 	GetConfig() (bool, float64, int)
+	GetRoutes() []string
+}, latencyToggle interface {
+	SetConfig(enabled bool, minMs, maxMs int)
+	GetConfig() (bool, int, int)
+	StartRamp(startMs, endMs int, duration, interval time.Duration)
+}, memoryToggle interface {
+	SetConfig(enabled bool, megabytes int)
+	GetConfig() (bool, int)
 }) *ToggleHandlers {
 	return &ToggleHandlers{
-		logger:      logger,
-		errorToggle: errorToggle,
+		logger:        logger,
+		errorToggle:   errorToggle,
+		latencyToggle: latencyToggle,
+		memoryToggle:  memoryToggle,
 	}
 }
 
 // ErrorRate handles POST /api/v1/toggles/error-rate - configures error injection
 func (h *ToggleHandlers) ErrorRate(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Enabled    bool    `json:"enabled"`
-		Rate       float64 `json:"rate"`
-		StatusCode int     `json:"status_code"`
+		Enabled    bool     `json:"enabled"`
+		Rate       float64  `json:"rate"`
+		StatusCode int      `json:"status_code"`
+		Routes     []string `json:"routes"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to decode error rate toggle request", zap.Error(err))
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	// Validate rate is between 0.0 and 1.0
 	if req.Rate < 0.0 || req.Rate > 1.0 {
-		http.Error(w, "Rate must be between 0.0 and 1.0", http.StatusBadRequest)
+		writeJSONError(w, r, http.StatusBadRequest, "Rate must be between 0.0 and 1.0")
 		return
 	}
 
 	// Validate status code is a valid 5xx error code
 	if req.StatusCode < 500 || req.StatusCode > 599 {
-		http.Error(w, "Status code must be between 500 and 599", http.StatusBadRequest)
+		writeJSONError(w, r, http.StatusBadRequest, "Status code must be between 500 and 599")
 		return
 	}
 
+	// Validate routes are known route patterns; none means all routes
+	for _, route := range req.Routes {
+		if route == "" || !h.knownRoutes[route] {
+			writeJSONError(w, r, http.StatusBadRequest, "Unknown route pattern: "+strconv.Quote(route))
+			return
+		}
+	}
+
 	// Update the error toggle configuration
-	h.errorToggle.SetConfig(req.Enabled, req.Rate, req.StatusCode)
+	h.errorToggle.SetConfig(req.Enabled, req.Rate, req.StatusCode, req.Routes...)
 
 	h.logger.Info("Error injection toggle updated",
 		zap.Bool("enabled", req.Enabled),
 		zap.Float64("rate", req.Rate),
 		zap.Int("status_code", req.StatusCode),
+		zap.Strings("routes", req.Routes),
 	)
 
 	response := map[string]interface{}{
 		"enabled":     req.Enabled,
 		"rate":        req.Rate,
 		"status_code": req.StatusCode,
+		"routes":      req.Routes,
 		"message":     "Error injection toggle updated",
+		"request_id":  requestIDFromContext(r.Context()),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// GetErrorRate handles GET /api/v1/toggles/error-rate - returns the current configuration
+func (h *ToggleHandlers) GetErrorRate(w http.ResponseWriter, r *http.Request) {
+	enabled, rate, statusCode := h.errorToggle.GetConfig()
+
+	response := map[string]interface{}{
+		"enabled":     enabled,
+		"rate":        rate,
+		"status_code": statusCode,
+		"routes":      h.errorToggle.GetRoutes(),
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		"request_id":  requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// Latency handles POST /api/v1/toggles/latency
+func (h *ToggleHandlers) Latency(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+		MinMs   int  `json:"min_ms"`
+		MaxMs   int  `json:"max_ms"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode latency toggle request", zap.Error(err))
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Validate the delay range
+	if req.MinMs < 0 || req.MaxMs < 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "min_ms and max_ms must be non-negative")
+		return
+	}
+	if req.MinMs > req.MaxMs {
+		writeJSONError(w, r, http.StatusBadRequest, "min_ms must not exceed max_ms")
+		return
+	}
+
+	// Update the latency toggle configuration
+	h.latencyToggle.SetConfig(req.Enabled, req.MinMs, req.MaxMs)
+
+	h.logger.Info("Latency injection toggle updated",
+		zap.Bool("enabled", req.Enabled),
+		zap.Int("min_ms", req.MinMs),
+		zap.Int("max_ms", req.MaxMs),
+	)
+
+	response := map[string]interface{}{
+		"enabled":    req.Enabled,
+		"min_ms":     req.MinMs,
+		"max_ms":     req.MaxMs,
+		"message":    "Latency injection toggle updated",
+		"request_id": requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// maxMemoryMegabytes bounds the allocation the memory toggle may hold
+const maxMemoryMegabytes = 2048
+
+// Memory handles POST /api/v1/toggles/memory - holds an allocation to simulate memory pressure
+func (h *ToggleHandlers) Memory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled   bool `json:"enabled"`
+		Megabytes int  `json:"megabytes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode memory toggle request", zap.Error(err))
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Validate the allocation size
+	if req.Megabytes < 0 || req.Megabytes > maxMemoryMegabytes {
+		writeJSONError(w, r, http.StatusBadRequest, "megabytes must be between 0 and "+strconv.Itoa(maxMemoryMegabytes))
+		return
+	}
+
+	// Update the memory toggle configuration
+	h.memoryToggle.SetConfig(req.Enabled, req.Megabytes)
+
+	h.logger.Info("Memory pressure toggle updated",
+		zap.Bool("enabled", req.Enabled),
+		zap.Int("megabytes", req.Megabytes),
+	)
+
+	response := map[string]interface{}{
+		"enabled":    req.Enabled,
+		"megabytes":  req.Megabytes,
+		"message":    "Memory pressure toggle updated",
+		"request_id": requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// latencyRampInterval is how often a latency ramp updates the injected delay
+const latencyRampInterval = time.Second
+
+// LatencyRamp handles POST /api/v1/chaos/latency-ramp - linearly increases the
+// injected latency over a window, then restores the previous configuration
+func (h *ToggleHandlers) LatencyRamp(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		StartMs   int `json:"start_ms"`
+		EndMs     int `json:"end_ms"`
+		DurationS int `json:"duration_s"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode latency ramp request", zap.Error(err))
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Validate the ramp
+	if req.StartMs < 0 || req.EndMs < 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "start_ms and end_ms must be non-negative")
+		return
+	}
+	if req.DurationS <= 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "duration_s must be positive")
+		return
+	}
+
+	h.latencyToggle.StartRamp(req.StartMs, req.EndMs, time.Duration(req.DurationS)*time.Second, latencyRampInterval)
+
+	h.logger.Info("Latency ramp started",
+		zap.Int("start_ms", req.StartMs),
+		zap.Int("end_ms", req.EndMs),
+		zap.Int("duration_s", req.DurationS),
+	)
+
+	response := map[string]interface{}{
+		"start_ms":   req.StartMs,
+		"end_ms":     req.EndMs,
+		"duration_s": req.DurationS,
+		"message":    "Latency ramp started",
+		"request_id": requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusAccepted, response)
+}
+
+// AdminHandlers contains administrative HTTP handlers
+type AdminHandlers struct {
+	logger *zap.Logger
+	tokens *TokenStore
+}
+
+// NewAdminHandlers creates new admin handlers
+func NewAdminHandlers(logger *zap.Logger, tokens *TokenStore) *AdminHandlers {
+	return &AdminHandlers{
+		logger: logger,
+		tokens: tokens,
+	}
+}
+
+// RotateToken handles POST /api/v1/admin/token - replaces the admin token
+func (h *AdminHandlers) RotateToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode token rotation request", zap.Error(err))
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Validate the new token is not empty
+	if strings.TrimSpace(req.Token) == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "Token must not be empty")
+		return
+	}
+
+	h.tokens.Set(req.Token)
+
+	h.logger.Info("Admin token rotated")
+
+	response := map[string]interface{}{
+		"message":    "Admin token rotated",
+		"request_id": requestIDFromContext(r.Context()),
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// LogLevelHandler handles POST /api/v1/loglevel - changes the log level of
+// one component, e.g. {"component": "http", "level": "debug"}
+func LogLevelHandler(logger *zap.Logger, levels *logging.Levels) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Component string `json:"component"`
+			Level     string `json:"level"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Failed to decode log level request", zap.Error(err))
+			writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		if strings.TrimSpace(req.Component) == "" {
+			writeJSONError(w, r, http.StatusBadRequest, "Component must not be empty")
+			return
+		}
+
+		level, err := zapcore.ParseLevel(req.Level)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, "Level must be one of debug, info, warn, error")
+			return
+		}
+
+		levels.SetLevel(req.Component, level)
+
+		logger.Info("Log level changed",
+			zap.String("component", req.Component),
+			zap.String("level", level.String()))
+
+		response := map[string]interface{}{
+			"component":  req.Component,
+			"level":      level.String(),
+			"request_id": requestIDFromContext(r.Context()),
+		}
+
+		httpjson.Write(w, r, http.StatusOK, response)
+	}
+}
+
+// Bounds for the duration of an on-demand CPU profile
+const (
+	defaultCPUProfileSeconds = 5
+	maxCPUProfileSeconds     = 60
+)
+
+// CPUProfile handles GET /api/v1/profile/cpu - captures a CPU profile for
+// ?seconds=N (1-60, default 5) and returns it in pprof format
+func (h *AdminHandlers) CPUProfile(w http.ResponseWriter, r *http.Request) {
+	seconds := defaultCPUProfileSeconds
+	if secondsParam := r.URL.Query().Get("seconds"); secondsParam != "" {
+		n, err := strconv.Atoi(secondsParam)
+		if err != nil || n < 1 || n > maxCPUProfileSeconds {
+			writeJSONError(w, r, http.StatusBadRequest, "seconds must be between 1 and 60")
+			return
+		}
+		seconds = n
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		// Only one CPU profile can run at a time
+		writeJSONError(w, r, http.StatusConflict, "CPU profile already in progress")
+		return
+	}
+
+	h.logger.Info("Capturing CPU profile", zap.Int("seconds", seconds))
+
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+		pprof.StopCPUProfile()
+		h.logger.Warn("CPU profile cancelled", zap.Error(r.Context().Err()))
+		return
+	}
+	pprof.StopCPUProfile()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="cpu.pprof"`)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+	w.Write(buf.Bytes())
+}
+
+// MiddlewareChainHandler serves the applied middleware in order, each with
+// the scope of routes it applies to
+func MiddlewareChainHandler(chain []middlewareEntry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"middleware": chain,
+		}
+
+		httpjson.Write(w, r, http.StatusOK, response)
+	}
+}
+
+// routeInfo is one method and pattern registered on the router
+type routeInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+}
+
+// RoutesHandler serves every method and route pattern registered on routes,
+// sorted by pattern. The router is walked on each request, so the listing
+// includes itself and any route registered after the handler was created.
+func RoutesHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := []routeInfo{}
+		chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			list = append(list, routeInfo{Method: method, Pattern: route})
+			return nil
+		})
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Pattern != list[j].Pattern {
+				return list[i].Pattern < list[j].Pattern
+			}
+			return list[i].Method < list[j].Method
+		})
+
+		httpjson.Write(w, r, http.StatusOK, map[string]interface{}{
+			"routes": list,
+		})
+	}
+}
+
+// buildDependency is a module compiled into the binary
+type buildDependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// BuildInfo handles GET /api/v1/buildinfo - reports the Go version, build
+// settings and module dependencies compiled into the binary
+func BuildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, "Build info not available")
+		return
+	}
+
+	dependencies := make([]buildDependency, 0, len(info.Deps))
+	for _, dep := range info.Deps {
+		d := buildDependency{Path: dep.Path, Version: dep.Version, Sum: dep.Sum}
+		if dep.Replace != nil {
+			d.Replace = dep.Replace.Path + "@" + dep.Replace.Version
+		}
+		dependencies = append(dependencies, d)
+	}
+
+	settings := make(map[string]string, len(info.Settings))
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+
+	response := map[string]interface{}{
+		"go_version": info.GoVersion,
+		"path":       info.Path,
+		"main": buildDependency{
+			Path:    info.Main.Path,
+			Version: info.Main.Version,
+			Sum:     info.Main.Sum,
+		},
+		"settings":     settings,
+		"dependencies": dependencies,
+	}
+
+	httpjson.Write(w, r, http.StatusOK, response)
+}
+
+// errorResponse is the JSON body of router-level error responses
+type errorResponse struct {
+	Error     string `json:"error"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeJSONError writes an errorResponse with the given status code, echoing
+// the request ID so clients can quote it when reporting failures
+func writeJSONError(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	httpjson.Write(w, r, statusCode, errorResponse{Error: message, Status: statusCode, RequestID: requestIDFromContext(r.Context())})
+}
+
+// NotFoundHandler responds with a JSON 404 for paths no route matches
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	// Inside a mounted sub-router the request has matched its "/prefix/*"
+	// pattern; clear it so metrics count the request as unmatched
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		rctx.RoutePatterns = nil
+	}
+	writeJSONError(w, r, http.StatusNotFound, "Not found")
+}
+
+// routeMethods lists the methods checked when building the Allow header
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// MethodNotAllowedHandler responds with a JSON 405 and an Allow header listing
+// the methods routes serves for the request path. It runs before any route
+// middleware, so authentication never challenges a request with the wrong method.
+func MethodNotAllowedHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range routeMethods {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeJSONError(w, r, http.StatusMethodNotAllowed, "Method "+r.Method+" not allowed")
+	}
+}
@@ -1,21 +1,31 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"monitoring-dashboard-automation/internal/health"
+	"monitoring-dashboard-automation/internal/logging"
 	"monitoring-dashboard-automation/internal/metrics"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	dto "github.com/prometheus/client_model/go"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
 )
 
 func TestNewHealthHandlers(t *testing.T) {
@@ -192,6 +202,44 @@ func TestHealthHandlers_ToggleReadiness_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestHealthHandlers_ToggleDeadlock(t *testing.T) {
+	checker := health.NewChecker()
+	handlers := NewHealthHandlers(checker)
+	
+	toggle := func(enabled string) {
+		req := httptest.NewRequest("POST", "/api/v1/toggles/deadlock", strings.NewReader(`{"enabled": `+enabled+`}`))
+		w := httptest.NewRecorder()
+		handlers.ToggleDeadlock(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	
+	liveness := func(target string) int {
+		w := httptest.NewRecorder()
+		handlers.Liveness(w, httptest.NewRequest("GET", target, nil))
+		return w.Code
+	}
+	
+	toggle("true")
+	defer checker.SetSimulatedDeadlock(false)
+	
+	if code := liveness("/healthz?deep=true"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected deep liveness status %d while deadlocked, got %d", http.StatusServiceUnavailable, code)
+	}
+	
+	// Shallow liveness does not probe the lock
+	if code := liveness("/healthz"); code != http.StatusOK {
+		t.Errorf("Expected shallow liveness status %d, got %d", http.StatusOK, code)
+	}
+	
+	toggle("false")
+	
+	if code := liveness("/healthz?deep=true"); code != http.StatusOK {
+		t.Errorf("Expected deep liveness status %d after disabling, got %d", http.StatusOK, code)
+	}
+}
+
 func TestHealthHandlers_Integration_ToggleAndCheck(t *testing.T) {
 	checker := health.NewChecker()
 	handlers := NewHealthHandlers(checker)
@@ -269,7 +317,7 @@ func containsAt(s, substr string) bool {
 func TestNewAPIHandlers(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	if handlers == nil {
 		t.Fatal("NewAPIHandlers() returned nil")
@@ -287,7 +335,7 @@ func TestNewAPIHandlers(t *testing.T) {
 func TestAPIHandlers_Ping(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	req := httptest.NewRequest("GET", "/api/v1/ping", nil)
 	w := httptest.NewRecorder()
@@ -316,10 +364,176 @@ func TestAPIHandlers_Ping(t *testing.T) {
 	}
 }
 
+func TestAPIHandlers_Ping_Accept(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{DefaultMs: 100})
+	
+	tests := []struct {
+		name      string
+		accept    string
+		plainText bool
+	}{
+		{name: "no Accept header", accept: "", plainText: false},
+		{name: "json", accept: "application/json", plainText: false},
+		{name: "any", accept: "*/*", plainText: false},
+		{name: "plain text", accept: "text/plain", plainText: true},
+		{name: "plain text preferred", accept: "application/json;q=0.5, text/plain", plainText: true},
+		{name: "json preferred", accept: "text/plain;q=0.8, application/json", plainText: false},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			
+			handlers.Ping(w, req)
+			
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			
+			if tt.plainText {
+				if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+					t.Errorf("Expected text/plain Content-Type, got %q", contentType)
+				}
+				if body := w.Body.String(); body != "pong" {
+					t.Errorf("Expected body 'pong', got %q", body)
+				}
+				return
+			}
+			
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected Content-Type 'application/json', got %q", contentType)
+			}
+			var response map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response["message"] != "pong" {
+				t.Errorf("Expected message 'pong', got %v", response["message"])
+			}
+			if _, ok := response["timestamp"]; !ok {
+				t.Error("Expected timestamp field in JSON response")
+			}
+		})
+	}
+}
+
+// failingResponseWriter is a ResponseWriter whose body writes always fail
+type failingResponseWriter struct {
+	header http.Header
+}
+
+func (f *failingResponseWriter) Header() http.Header {
+	if f.header == nil {
+		f.header = make(http.Header)
+	}
+	return f.header
+}
+
+func (f *failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken writer")
+}
+
+func (f *failingResponseWriter) WriteHeader(int) {}
+
+func TestAPIHandlers_Ping_EncodeError(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// Routed through chi so the failure is labelled with the route pattern
+	r := chi.NewRouter()
+	r.Use(JSONEncodeErrorMiddleware(logger, metricsRegistry))
+	r.Get("/api/v1/ping", handlers.Ping)
+	
+	req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+	r.ServeHTTP(&failingResponseWriter{}, req)
+	
+	metricsW := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(metricsW, httptest.NewRequest("GET", "/metrics", nil))
+	
+	if !strings.Contains(metricsW.Body.String(), `http_json_encode_errors_total{endpoint="/api/v1/ping"} 1`) {
+		t.Error("Expected http_json_encode_errors_total to be incremented for /api/v1/ping")
+	}
+}
+
+func TestAPIHandlers_Echo_Formats(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{DefaultMs: 100})
+	
+	tests := []struct {
+		format      string
+		contentType string
+		decode      func([]byte, interface{}) error
+	}{
+		{format: "", contentType: "application/json", decode: json.Unmarshal},
+		{format: "json", contentType: "application/json", decode: json.Unmarshal},
+		{format: "yaml", contentType: "application/yaml", decode: yaml.Unmarshal},
+		{format: "text", contentType: "text/plain"},
+	}
+	
+	for _, tt := range tests {
+		t.Run("format="+tt.format, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/echo?format="+tt.format+"&name=demo", nil)
+			req.Header.Set("X-Custom", "value")
+			req.Header.Set("Authorization", "Bearer secret-token")
+			w := httptest.NewRecorder()
+			
+			handlers.Echo(w, req)
+			
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if w.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Expected Content-Type '%s', got '%s'", tt.contentType, w.Header().Get("Content-Type"))
+			}
+			if strings.Contains(w.Body.String(), "secret-token") {
+				t.Error("Echo must not reflect the Authorization header")
+			}
+			
+			if tt.decode == nil {
+				if !strings.Contains(w.Body.String(), "query.name: demo\n") || !strings.Contains(w.Body.String(), "header.X-Custom: value\n") {
+					t.Errorf("Expected query and header lines in text body, got %q", w.Body.String())
+				}
+				return
+			}
+			
+			var response echoResponse
+			if err := tt.decode(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Method != "GET" || response.Path != "/api/v1/echo" {
+				t.Errorf("Expected method and path to round-trip, got %+v", response)
+			}
+			if got := response.Query["name"]; len(got) != 1 || got[0] != "demo" {
+				t.Errorf("Expected query name=demo to round-trip, got %v", response.Query)
+			}
+			if got := response.Headers["X-Custom"]; len(got) != 1 || got[0] != "value" {
+				t.Errorf("Expected X-Custom header to round-trip, got %v", response.Headers)
+			}
+		})
+	}
+}
+
+func TestAPIHandlers_Echo_UnknownFormat(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{DefaultMs: 100})
+	
+	req := httptest.NewRequest("GET", "/api/v1/echo?format=xml", nil)
+	w := httptest.NewRecorder()
+	
+	handlers.Echo(w, req)
+	
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
 func TestAPIHandlers_Work_DefaultParameters(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	req := httptest.NewRequest("GET", "/api/v1/work", nil)
 	w := httptest.NewRecorder()
@@ -352,161 +566,1078 @@ func TestAPIHandlers_Work_DefaultParameters(t *testing.T) {
 	if response["jitter_ms"] != float64(0) {
 		t.Errorf("Expected jitter_ms 0, got %v", response["jitter_ms"])
 	}
-	
-	// Check that it actually took approximately the right amount of time
-	if duration < 90*time.Millisecond || duration > 150*time.Millisecond {
-		t.Errorf("Expected duration around 100ms, got %v", duration)
+	
+	// Check that it actually took approximately the right amount of time
+	if duration < 90*time.Millisecond || duration > 150*time.Millisecond {
+		t.Errorf("Expected duration around 100ms, got %v", duration)
+	}
+	
+	if _, ok := response["timestamp"]; !ok {
+		t.Error("Expected timestamp field in response")
+	}
+	
+	if _, ok := response["actual_duration_ms"]; !ok {
+		t.Error("Expected actual_duration_ms field in response")
+	}
+}
+
+func TestAPIHandlers_Work_ConfiguredDefaults(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 200, DefaultJitter: 20})
+	
+	req := httptest.NewRequest("GET", "/api/v1/work", nil)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	handlers.Work(w, req)
+	duration := time.Since(start)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if response["requested_ms"] != float64(200) {
+		t.Errorf("Expected requested_ms 200 (configured default), got %v", response["requested_ms"])
+	}
+	
+	if response["jitter_ms"] != float64(20) {
+		t.Errorf("Expected jitter_ms 20 (configured default), got %v", response["jitter_ms"])
+	}
+	
+	// Duration should be between 200ms and 220ms (200ms + up to 20ms jitter)
+	if duration < 190*time.Millisecond || duration > 260*time.Millisecond {
+		t.Errorf("Expected duration between 200-220ms, got %v", duration)
+	}
+	
+	// Explicit parameters still override the configured defaults
+	req = httptest.NewRequest("GET", "/api/v1/work?ms=0&jitter=0", nil)
+	w = httptest.NewRecorder()
+	handlers.Work(w, req)
+	
+	response = nil
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if response["requested_ms"] != float64(0) {
+		t.Errorf("Expected requested_ms 0 when given explicitly, got %v", response["requested_ms"])
+	}
+}
+
+func TestAPIHandlers_Work_CustomParameters(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// Test with custom ms and jitter parameters
+	params := url.Values{}
+	params.Add("ms", "200")
+	params.Add("jitter", "50")
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	handlers.Work(w, req)
+	duration := time.Since(start)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if response["requested_ms"] != float64(200) {
+		t.Errorf("Expected requested_ms 200, got %v", response["requested_ms"])
+	}
+	
+	if response["jitter_ms"] != float64(50) {
+		t.Errorf("Expected jitter_ms 50, got %v", response["jitter_ms"])
+	}
+	
+	// Duration should be between 200ms and 250ms (200ms + up to 50ms jitter)
+	if duration < 190*time.Millisecond || duration > 280*time.Millisecond {
+		t.Errorf("Expected duration between 200-250ms, got %v", duration)
+	}
+}
+
+func TestAPIHandlers_Work_JitterMetric(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+
+	const requests = 20
+	for i := 0; i < requests; i++ {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&jitter=100", nil)
+		w := httptest.NewRecorder()
+		handlers.Work(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+
+	histogram := findHistogram(t, metricsRegistry, "work_jitter_applied_seconds")
+
+	if histogram.GetSampleCount() != requests {
+		t.Fatalf("Expected %d jitter observations, got %d", requests, histogram.GetSampleCount())
+	}
+
+	if histogram.GetSampleSum() <= 0 {
+		t.Errorf("Expected positive jitter sum, got %f", histogram.GetSampleSum())
+	}
+
+	// Every observation must fall within the requested 0-100ms window
+	for _, bucket := range histogram.GetBucket() {
+		if bucket.GetUpperBound() == 0.1 && bucket.GetCumulativeCount() != requests {
+			t.Errorf("Expected all %d observations <= 0.1s, got %d", requests, bucket.GetCumulativeCount())
+		}
+	}
+}
+
+func TestAPIHandlers_Work_InflightSnapshot(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+
+	// Start each request once the previous ones are running, so they overlap
+	const requests = 4
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handlers.Work(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/work?ms=300", nil))
+		}()
+
+		deadline := time.Now().Add(time.Second)
+		for metricsRegistry.GetInflightJobs() < float64(i+1) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	histogram := findHistogram(t, metricsRegistry, "work_jobs_inflight_snapshot")
+
+	if histogram.GetSampleCount() != requests {
+		t.Fatalf("Expected %d snapshots, got %d", requests, histogram.GetSampleCount())
+	}
+
+	// The snapshots are 0, 1, 2 and 3, so two of them are greater than 1
+	for _, bucket := range histogram.GetBucket() {
+		if bucket.GetUpperBound() == 1 && bucket.GetCumulativeCount() != 2 {
+			t.Errorf("Expected 2 snapshots greater than 1, got %d", requests-bucket.GetCumulativeCount())
+		}
+	}
+}
+
+// findHistogram returns the first histogram sample of the named metric family
+func findHistogram(t *testing.T, metricsRegistry *metrics.Registry, name string) *dto.Histogram {
+	t.Helper()
+
+	families, err := metricsRegistry.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) > 0 {
+			return family.GetMetric()[0].GetHistogram()
+		}
+	}
+
+	t.Fatalf("Metric %s not found", name)
+	return nil
+}
+
+// findCounter returns the value of the named unlabelled counter
+func findCounter(t *testing.T, metricsRegistry *metrics.Registry, name string) float64 {
+	t.Helper()
+
+	families, err := metricsRegistry.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) > 0 {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+
+	t.Fatalf("Metric %s not found", name)
+	return 0
+}
+
+func TestAPIHandlers_Work_CompletedAndCancelledCounters(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// A request that runs to completion
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=1", nil)
+	w := httptest.NewRecorder()
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	// A request cancelled before the work finishes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	
+	req = httptest.NewRequest("GET", "/api/v1/work?ms=200", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	
+	if got := findCounter(t, metricsRegistry, "work_completed_total"); got != 1 {
+		t.Errorf("Expected work_completed_total 1, got %v", got)
+	}
+	if got := findCounter(t, metricsRegistry, "work_cancelled_total"); got != 1 {
+		t.Errorf("Expected work_cancelled_total 1, got %v", got)
+	}
+}
+
+func TestAPIHandlers_Work_InvalidParameters(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// Test with invalid parameters - should use defaults
+	params := url.Values{}
+	params.Add("ms", "invalid")
+	params.Add("jitter", "also_invalid")
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
+	w := httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	// Should fall back to defaults
+	if response["requested_ms"] != float64(100) {
+		t.Errorf("Expected requested_ms 100 (default), got %v", response["requested_ms"])
+	}
+	
+	if response["jitter_ms"] != float64(0) {
+		t.Errorf("Expected jitter_ms 0 (default), got %v", response["jitter_ms"])
+	}
+}
+
+func TestAPIHandlers_Work_NegativeParameters(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// Test with negative parameters - should use defaults
+	params := url.Values{}
+	params.Add("ms", "-100")
+	params.Add("jitter", "-50")
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
+	w := httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	// Should fall back to defaults for negative values
+	if response["requested_ms"] != float64(100) {
+		t.Errorf("Expected requested_ms 100 (default), got %v", response["requested_ms"])
+	}
+	
+	if response["jitter_ms"] != float64(0) {
+		t.Errorf("Expected jitter_ms 0 (default), got %v", response["jitter_ms"])
+	}
+}
+
+func TestAPIHandlers_Work_SuccessStatus(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0&status=202", nil)
+	w := httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if response["message"] != "work completed" {
+		t.Errorf("Expected message 'work completed', got %v", response["message"])
+	}
+}
+
+func TestAPIHandlers_Work_InvalidSuccessStatus(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	for _, status := range []string{"500", "302", "abc"} {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&status="+status, nil)
+		w := httptest.NewRecorder()
+		
+		handlers.Work(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status=%s: expected status %d, got %d", status, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_Work_InjectionReport(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// Error injection is active but does not fire for this request
+	toggle := &mockErrorToggle{shouldInject: false}
+	handler := ErrorInjectionMiddleware(toggle, metricsRegistry)(http.HandlerFunc(handlers.Work))
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0", nil)
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var response struct {
+		Injection InjectionInfo `json:"injection"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if response.Injection.Error {
+		t.Error("Expected injection.error to be false")
+	}
+	if response.Injection.LatencyMs != 0 {
+		t.Errorf("Expected injection.latency_ms 0, got %d", response.Injection.LatencyMs)
+	}
+}
+
+func TestAPIHandlers_Work_LatencyInjectionReport(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	toggle := &mockDelayToggle{delay: 20 * time.Millisecond}
+	handler := LatencyInjectionMiddleware(toggle)(http.HandlerFunc(handlers.Work))
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0", nil)
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	var response struct {
+		Injection InjectionInfo `json:"injection"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if response.Injection.LatencyMs != 20 {
+		t.Errorf("Expected injection.latency_ms 20, got %d", response.Injection.LatencyMs)
+	}
+}
+
+func TestAPIHandlers_Work_TruncatedResponse(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	server := httptest.NewServer(http.HandlerFunc(handlers.Work))
+	defer server.Close()
+	
+	resp, err := http.Get(server.URL + "/api/v1/work?ms=0&truncate_after=10")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Error("Expected an error reading the truncated body, got none")
+	}
+	
+	if len(body) != 10 {
+		t.Errorf("Expected 10 body bytes, got %d (%q)", len(body), body)
+	}
+	
+	if resp.ContentLength <= 10 {
+		t.Errorf("Expected advertised Content-Length above 10, got %d", resp.ContentLength)
+	}
+}
+
+func TestAPIHandlers_Work_InvalidTruncateAfter(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0&truncate_after=-1", nil)
+	w := httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAPIHandlers_Work_ContextCancellation(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	// Create a request with a short timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	
+	params := url.Values{}
+	params.Add("ms", "200") // Request 200ms of work, but timeout after 50ms
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	
+	body := w.Body.String()
+	if !contains(body, "Work simulation cancelled") {
+		t.Errorf("Expected body to contain 'Work simulation cancelled', got '%s'", body)
+	}
+}
+
+func TestAPIHandlers_Work_CPUMode(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=200&mode=cpu", nil)
+	w := httptest.NewRecorder()
+	
+	before := processCPUTime(t)
+	handlers.Work(w, req)
+	used := processCPUTime(t) - before
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	
+	// Sleeping would use next to no CPU; allow for coarse accounting
+	if used < 100*time.Millisecond {
+		t.Errorf("Expected cpu mode to burn at least 100ms of CPU, used %v", used)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["mode"] != "cpu" {
+		t.Errorf("Expected mode 'cpu', got %v", response["mode"])
+	}
+}
+
+func TestAPIHandlers_Work_CPUModeContextCancellation(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=5000&mode=cpu", nil)
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cpu work to stop soon after the timeout, took %v", elapsed)
+	}
+}
+
+func TestAPIHandlers_Work_Mode(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0", nil)
+	w := httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["mode"] != "sleep" {
+		t.Errorf("Expected default mode 'sleep', got %v", response["mode"])
+	}
+	
+	req = httptest.NewRequest("GET", "/api/v1/work?ms=0&mode=gpu", nil)
+	w = httptest.NewRecorder()
+	
+	handlers.Work(w, req)
+	
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for unknown mode, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// processCPUTime returns the user and system CPU time used by the test process
+func processCPUTime(t *testing.T) time.Duration {
+	t.Helper()
+	
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		t.Fatalf("Getrusage failed: %v", err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+func TestAPIHandlers_Work_PanicRate(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	handler := PanicRecoveryMiddleware(logger, metricsRegistry)(http.HandlerFunc(handlers.Work))
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0&panic_rate=1.0", nil)
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d from panic recovery, got %d", http.StatusInternalServerError, w.Code)
+	}
+	
+	// A zero rate never panics
+	req = httptest.NewRequest("GET", "/api/v1/work?ms=0&panic_rate=0", nil)
+	w = httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAPIHandlers_Work_InvalidPanicRate(t *testing.T) {
+	logger := zap.NewNop()
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
+	
+	for _, rate := range []string{"-0.1", "1.5", "often"} {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&panic_rate="+rate, nil)
+		w := httptest.NewRecorder()
+		
+		handlers.Work(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("panic_rate=%s: expected status %d, got %d", rate, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_Work_FailRate(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(zap.NewNop(), metricsRegistry, WorkConfig{})
+	
+	// fail=1.0 always fails, with the default or requested status
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&jitter=0&fail=1.0", nil)
+		w := httptest.NewRecorder()
+		
+		handlers.Work(w, req)
+		
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+	}
+	
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0&jitter=0&fail=1.0&fail_status=503", nil)
+	w := httptest.NewRecorder()
+	handlers.Work(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected fail_status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	
+	// fail=0.0 never fails
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&jitter=0&fail=0.0", nil)
+		w := httptest.NewRecorder()
+		
+		handlers.Work(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	
+	metricsReq := httptest.NewRequest("GET", "/metrics", nil)
+	metricsW := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(metricsW, metricsReq)
+	
+	if !strings.Contains(metricsW.Body.String(), `work_failures_total{operation="injected_failure"} 6`) {
+		t.Error("Expected every failed request in work_failures_total")
+	}
+	if completed := findCounter(t, metricsRegistry, "work_completed_total"); completed != 5 {
+		t.Errorf("Expected only successful requests counted as completed, got %v", completed)
+	}
+}
+
+func TestAPIHandlers_Work_InvalidFail(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	
+	for _, query := range []string{"fail=-0.1", "fail=1.5", "fail=sometimes", "fail=1&fail_status=200", "fail=1&fail_status=600"} {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&"+query, nil)
+		w := httptest.NewRecorder()
+		
+		handlers.Work(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_BatchWork(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(zap.NewNop(), metricsRegistry, WorkConfig{})
+	
+	// Sample the inflight gauge while the batch runs
+	var maxInflight float64
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if inflight := metricsRegistry.GetInflightJobs(); inflight > maxInflight {
+				maxInflight = inflight
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	
+	req := httptest.NewRequest("POST", "/api/v1/work/batch", strings.NewReader(`{"count": 6, "ms": 50, "jitter": 0, "concurrency": 2}`))
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	handlers.BatchWork(w, req)
+	elapsed := time.Since(start)
+	close(stop)
+	<-sampled
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	
+	// Six 50ms jobs two at a time take at least three rounds
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("Expected at least 150ms with concurrency 2, took %v", elapsed)
+	}
+	if maxInflight > 2 {
+		t.Errorf("Expected at most 2 jobs in flight, saw %v", maxInflight)
+	}
+	if maxInflight == 0 {
+		t.Error("Expected work_jobs_inflight to rise while jobs run")
+	}
+	if inflight := metricsRegistry.GetInflightJobs(); inflight != 0 {
+		t.Errorf("Expected no jobs in flight afterwards, got %v", inflight)
+	}
+	
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["completed"] != float64(6) || response["concurrency"] != float64(2) {
+		t.Errorf("Expected 6 jobs completed at concurrency 2, got %v", response)
+	}
+	if response["min_ms"].(float64) < 50 || response["wall_duration_ms"].(float64) < 150 {
+		t.Errorf("Unexpected timing stats %v", response)
+	}
+	if completed := findCounter(t, metricsRegistry, "work_completed_total"); completed != 6 {
+		t.Errorf("Expected 6 completed jobs, got %v", completed)
+	}
+}
+
+func TestAPIHandlers_BatchWork_Cancellation(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	handlers := NewAPIHandlers(zap.NewNop(), metricsRegistry, WorkConfig{})
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+	
+	req := httptest.NewRequest("POST", "/api/v1/work/batch", strings.NewReader(`{"count": 20, "ms": 50, "concurrency": 1}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	handlers.BatchWork(w, req)
+	elapsed := time.Since(start)
+	
+	if w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the remaining jobs to be skipped, took %v", elapsed)
+	}
+	
+	completed := findCounter(t, metricsRegistry, "work_completed_total")
+	cancelled := findCounter(t, metricsRegistry, "work_cancelled_total")
+	if completed+cancelled >= 20 {
+		t.Errorf("Expected jobs after the cancellation not to start, got %v completed and %v cancelled", completed, cancelled)
+	}
+	if cancelled != 1 {
+		t.Errorf("Expected the running job to be cancelled, got %v", cancelled)
+	}
+	if inflight := metricsRegistry.GetInflightJobs(); inflight != 0 {
+		t.Errorf("Expected no jobs in flight afterwards, got %v", inflight)
+	}
+}
+
+func TestAPIHandlers_BatchWork_InvalidRequest(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	
+	for _, body := range []string{
+		`not json`,
+		`{"count": 0}`,
+		`{"count": 1001}`,
+		`{"count": 5, "concurrency": 101}`,
+		`{"count": 5, "concurrency": -1}`,
+		`{"count": 5, "ms": -1}`,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/work/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		handlers.BatchWork(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// readSSEEvent reads the next event from an event stream
+func readSSEEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event.name != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatalf("Failed to decode event data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestAPIHandlers_Work_Stream(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	server := httptest.NewServer(middleware.RequestID(RequestIDMiddleware(http.HandlerFunc(handlers.Work))))
+	defer server.Close()
+	
+	resp, err := http.Get(server.URL + "/api/v1/work?ms=350&jitter=0&stream=true")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %q", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Expected Cache-Control no-cache, got %q", cc)
+	}
+	
+	reader := bufio.NewReader(resp.Body)
+	var progress []sseEvent
+	var event sseEvent
+	for {
+		event = readSSEEvent(t, reader)
+		if event.name != "progress" {
+			break
+		}
+		progress = append(progress, event)
+	}
+	
+	if len(progress) < 2 {
+		t.Fatalf("Expected several progress events before completion, got %d", len(progress))
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].data["percent"].(float64) < progress[i-1].data["percent"].(float64) ||
+			progress[i].data["elapsed_ms"].(float64) <= progress[i-1].data["elapsed_ms"].(float64) {
+			t.Errorf("Expected progress to advance, got %v then %v", progress[i-1].data, progress[i].data)
+		}
+	}
+	if percent := progress[len(progress)-1].data["percent"].(float64); percent <= 0 || percent >= 100 {
+		t.Errorf("Expected progress between 0 and 100 percent, got %v", percent)
 	}
 	
-	if _, ok := response["timestamp"]; !ok {
-		t.Error("Expected timestamp field in response")
+	if event.name != "done" {
+		t.Fatalf("Expected a done event after progress, got %q", event.name)
+	}
+	if event.data["message"] != "work completed" || event.data["requested_ms"] != float64(350) {
+		t.Errorf("Expected the work response in the done event, got %v", event.data)
+	}
+	if event.data["request_id"] != resp.Header.Get("X-Request-ID") {
+		t.Errorf("Expected request_id %q in the done event, got %v", resp.Header.Get("X-Request-ID"), event.data["request_id"])
 	}
 	
-	if _, ok := response["actual_duration_ms"]; !ok {
-		t.Error("Expected actual_duration_ms field in response")
+	if rest, _ := io.ReadAll(reader); len(rest) != 0 {
+		t.Errorf("Expected the stream to end after done, got %q", rest)
 	}
 }
 
-func TestAPIHandlers_Work_CustomParameters(t *testing.T) {
-	logger := zap.NewNop()
+func TestAPIHandlers_Work_StreamClientDisconnect(t *testing.T) {
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
-	
-	// Test with custom ms and jitter parameters
-	params := url.Values{}
-	params.Add("ms", "200")
-	params.Add("jitter", "50")
-	
-	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
-	w := httptest.NewRecorder()
+	handlers := NewAPIHandlers(zap.NewNop(), metricsRegistry, WorkConfig{})
 	
-	start := time.Now()
-	handlers.Work(w, req)
-	duration := time.Since(start)
+	finished := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(finished)
+		handlers.Work(w, r)
+	}))
+	defer server.Close()
 	
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/work?ms=10000&jitter=0&stream=true", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
 	}
 	
-	var response map[string]interface{}
-	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
-		t.Fatalf("Failed to decode response: %v", err)
+	if event := readSSEEvent(t, bufio.NewReader(resp.Body)); event.name != "progress" {
+		t.Fatalf("Expected a progress event, got %q", event.name)
 	}
 	
-	if response["requested_ms"] != float64(200) {
-		t.Errorf("Expected requested_ms 200, got %v", response["requested_ms"])
-	}
+	cancel()
+	resp.Body.Close()
 	
-	if response["jitter_ms"] != float64(50) {
-		t.Errorf("Expected jitter_ms 50, got %v", response["jitter_ms"])
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the handler to stop once the client disconnected")
 	}
 	
-	// Duration should be between 200ms and 250ms (200ms + up to 50ms jitter)
-	if duration < 190*time.Millisecond || duration > 280*time.Millisecond {
-		t.Errorf("Expected duration between 200-250ms, got %v", duration)
+	if cancelled := findCounter(t, metricsRegistry, "work_cancelled_total"); cancelled != 1 {
+		t.Errorf("Expected the streamed work to be cancelled, got %v", cancelled)
+	}
+	if inflight := metricsRegistry.GetInflightJobs(); inflight != 0 {
+		t.Errorf("Expected no jobs in flight afterwards, got %v", inflight)
 	}
 }
 
-func TestAPIHandlers_Work_InvalidParameters(t *testing.T) {
-	logger := zap.NewNop()
-	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
-	
-	// Test with invalid parameters - should use defaults
-	params := url.Values{}
-	params.Add("ms", "invalid")
-	params.Add("jitter", "also_invalid")
+func TestAPIHandlers_Work_InvalidStream(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	
+	for _, query := range []string{"stream=maybe", "stream=true&status=201", "stream=true&truncate_after=10"} {
+		req := httptest.NewRequest("GET", "/api/v1/work?ms=0&"+query, nil)
+		w := httptest.NewRecorder()
+		
+		handlers.Work(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestAPIHandlers_PrettyJSON(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{DefaultMs: 100})
+	
+	tests := []struct {
+		name     string
+		query    string
+		indented bool
+	}{
+		{name: "default compact", query: "", indented: false},
+		{name: "pretty", query: "?pretty=true", indented: true},
+		{name: "pretty false", query: "?pretty=false", indented: false},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/ping"+tt.query, nil)
+			w := httptest.NewRecorder()
+			
+			handlers.Ping(w, req)
+			
+			body := w.Body.Bytes()
+			if !json.Valid(body) {
+				t.Fatalf("Expected valid JSON, got %s", body)
+			}
+			if indented := bytes.Contains(body, []byte("\n  \"")); indented != tt.indented {
+				t.Errorf("Expected indented=%v, got %s", tt.indented, body)
+			}
+		})
+	}
+}
+
+func TestAPIHandlers_Work_ResponseTemplate(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
 	
-	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
+	template := `{"order":{"id":"A-17","items":[1,2,3],"paid":true},"note":null}`
+	body := `{"response_template":` + template + `}`
+	req := httptest.NewRequest("POST", "/api/v1/work?ms=0&jitter=0", strings.NewReader(body))
 	w := httptest.NewRecorder()
 	
 	handlers.Work(w, req)
 	
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 	
-	var response map[string]interface{}
+	var response struct {
+		ResponseTemplate json.RawMessage `json:"response_template"`
+		RequestedMs      *int            `json:"requested_ms"`
+		JitterMs         *int            `json:"jitter_ms"`
+		ActualDurationMs *int            `json:"actual_duration_ms"`
+	}
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 	
-	// Should fall back to defaults
-	if response["requested_ms"] != float64(100) {
-		t.Errorf("Expected requested_ms 100 (default), got %v", response["requested_ms"])
+	var compacted bytes.Buffer
+	json.Compact(&compacted, response.ResponseTemplate)
+	if compacted.String() != template {
+		t.Errorf("Expected the template verbatim, got %s", compacted.String())
 	}
-	
-	if response["jitter_ms"] != float64(0) {
-		t.Errorf("Expected jitter_ms 0 (default), got %v", response["jitter_ms"])
+	if response.RequestedMs == nil || response.JitterMs == nil || response.ActualDurationMs == nil {
+		t.Error("Expected the work timing fields alongside the template")
 	}
 }
 
-func TestAPIHandlers_Work_NegativeParameters(t *testing.T) {
-	logger := zap.NewNop()
-	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
-	
-	// Test with negative parameters - should use defaults
-	params := url.Values{}
-	params.Add("ms", "-100")
-	params.Add("jitter", "-50")
+func TestAPIHandlers_Work_WithoutResponseTemplate(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
 	
-	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
+	req := httptest.NewRequest("GET", "/api/v1/work?ms=0&jitter=0", nil)
 	w := httptest.NewRecorder()
 	
 	handlers.Work(w, req)
 	
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
-	}
-	
 	var response map[string]interface{}
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
-	
-	// Should fall back to defaults for negative values
-	if response["requested_ms"] != float64(100) {
-		t.Errorf("Expected requested_ms 100 (default), got %v", response["requested_ms"])
-	}
-	
-	if response["jitter_ms"] != float64(0) {
-		t.Errorf("Expected jitter_ms 0 (default), got %v", response["jitter_ms"])
+	if _, ok := response["response_template"]; ok {
+		t.Error("Expected no response_template without a POST body")
 	}
 }
 
-func TestAPIHandlers_Work_ContextCancellation(t *testing.T) {
-	logger := zap.NewNop()
-	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
-	
-	// Create a request with a short timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
-	defer cancel()
-	
-	params := url.Values{}
-	params.Add("ms", "200") // Request 200ms of work, but timeout after 50ms
-	
-	req := httptest.NewRequest("GET", "/api/v1/work?"+params.Encode(), nil)
-	req = req.WithContext(ctx)
-	w := httptest.NewRecorder()
-	
-	handlers.Work(w, req)
-	
-	if w.Code != http.StatusRequestTimeout {
-		t.Errorf("Expected status %d, got %d", http.StatusRequestTimeout, w.Code)
-	}
-	
-	body := w.Body.String()
-	if !contains(body, "Work simulation cancelled") {
-		t.Errorf("Expected body to contain 'Work simulation cancelled', got '%s'", body)
+func TestAPIHandlers_Work_InvalidResponseTemplate(t *testing.T) {
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"invalid JSON", `{"response_template":`, http.StatusBadRequest},
+		{"missing template", `{}`, http.StatusBadRequest},
+		{"not an object", `{"response_template":[1,2]}`, http.StatusBadRequest},
+		{"null template", `{"response_template":null}`, http.StatusBadRequest},
+		{"too large", `{"response_template":{"pad":"` + strings.Repeat("x", maxResponseTemplateBytes) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/work?ms=0", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			
+			handlers.Work(w, req)
+			
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
 	}
 }
 
 func TestAPIHandlers_Work_ZeroParameters(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	// Test with zero parameters
 	params := url.Values{}
@@ -546,7 +1677,7 @@ func TestAPIHandlers_Work_ZeroParameters(t *testing.T) {
 func TestAPIHandlers_SimulateWork(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	// Test normal completion
 	ctx := context.Background()
@@ -568,7 +1699,7 @@ func TestAPIHandlers_SimulateWork(t *testing.T) {
 func TestAPIHandlers_SimulateWork_Cancellation(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	// Test context cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -601,7 +1732,7 @@ func TestAPIHandlers_SimulateWork_Cancellation(t *testing.T) {
 func TestAPIHandlers_SimulateWork_Timeout(t *testing.T) {
 	logger := zap.NewNop()
 	metricsRegistry := metrics.NewRegistry()
-	handlers := NewAPIHandlers(logger, metricsRegistry)
+	handlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{DefaultMs: 100})
 	
 	// Test context timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
@@ -636,7 +1767,7 @@ func TestToggleHandlers_ErrorRate_ValidRequest(t *testing.T) {
 		statusCode: 500,
 	}
 	
-	handlers := NewToggleHandlers(logger, mockToggle)
+	handlers := NewToggleHandlers(logger, mockToggle, &mockLatencyToggle{}, &mockMemoryToggle{})
 	
 	// Create valid request
 	reqBody := `{"enabled": true, "rate": 0.5, "status_code": 503}`
@@ -690,7 +1821,7 @@ func TestToggleHandlers_ErrorRate_InvalidJSON(t *testing.T) {
 		statusCode: 500,
 	}
 	
-	handlers := NewToggleHandlers(logger, mockToggle)
+	handlers := NewToggleHandlers(logger, mockToggle, &mockLatencyToggle{}, &mockMemoryToggle{})
 	
 	// Create invalid JSON request
 	reqBody := `{"enabled": true, "rate": invalid}`
@@ -717,7 +1848,7 @@ func TestToggleHandlers_ErrorRate_InvalidRate(t *testing.T) {
 		statusCode: 500,
 	}
 	
-	handlers := NewToggleHandlers(logger, mockToggle)
+	handlers := NewToggleHandlers(logger, mockToggle, &mockLatencyToggle{}, &mockMemoryToggle{})
 	
 	// Create request with invalid rate (> 1.0)
 	reqBody := `{"enabled": true, "rate": 1.5, "status_code": 503}`
@@ -756,7 +1887,7 @@ func TestToggleHandlers_ErrorRate_InvalidStatusCode(t *testing.T) {
 		statusCode: 500,
 	}
 	
-	handlers := NewToggleHandlers(logger, mockToggle)
+	handlers := NewToggleHandlers(logger, mockToggle, &mockLatencyToggle{}, &mockMemoryToggle{})
 	
 	// Create request with invalid status code (< 500)
 	reqBody := `{"enabled": true, "rate": 0.5, "status_code": 400}`
@@ -785,19 +1916,438 @@ func TestToggleHandlers_ErrorRate_InvalidStatusCode(t *testing.T) {
 	}
 }
 
+func TestToggleHandlers_GetErrorRate(t *testing.T) {
+	mockToggle := &mockToggleInterface{
+		enabled:    true,
+		rate:       0.25,
+		statusCode: 503,
+		routes:     []string{"/api/v1/work"},
+	}
+	handlers := NewToggleHandlers(zap.NewNop(), mockToggle, &mockLatencyToggle{}, &mockMemoryToggle{})
+	
+	req := httptest.NewRequest("GET", "/api/v1/toggles/error-rate", nil)
+	w := httptest.NewRecorder()
+	
+	handlers.GetErrorRate(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", w.Header().Get("Content-Type"))
+	}
+	
+	var response struct {
+		Enabled    bool     `json:"enabled"`
+		Rate       float64  `json:"rate"`
+		StatusCode int      `json:"status_code"`
+		Routes     []string `json:"routes"`
+		Timestamp  string   `json:"timestamp"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	if !response.Enabled || response.Rate != 0.25 || response.StatusCode != 503 {
+		t.Errorf("Expected current config in response, got %+v", response)
+	}
+	if len(response.Routes) != 1 || response.Routes[0] != "/api/v1/work" {
+		t.Errorf("Expected routes [/api/v1/work], got %v", response.Routes)
+	}
+	if response.Timestamp == "" {
+		t.Error("Expected timestamp to be set")
+	}
+}
+
+func TestToggleHandlers_ErrorRate_ScopedRoutes(t *testing.T) {
+	mockToggle := &mockToggleInterface{}
+	handlers := NewToggleHandlers(zap.NewNop(), mockToggle, &mockLatencyToggle{}, &mockMemoryToggle{})
+	handlers.knownRoutes = map[string]bool{"/api/v1/work": true, "/api/v1/ping": true}
+	
+	reqBody := `{"enabled": true, "rate": 1.0, "status_code": 503, "routes": ["/api/v1/work"]}`
+	req := httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(reqBody))
+	w := httptest.NewRecorder()
+	
+	handlers.ErrorRate(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	
+	if len(mockToggle.routes) != 1 || mockToggle.routes[0] != "/api/v1/work" {
+		t.Errorf("Expected routes [/api/v1/work], got %v", mockToggle.routes)
+	}
+	
+	// Unknown and empty patterns are rejected
+	for _, routes := range []string{`["/api/v1/nope"]`, `[""]`} {
+		reqBody = `{"enabled": true, "rate": 1.0, "status_code": 503, "routes": ` + routes + `}`
+		req = httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(reqBody))
+		w = httptest.NewRecorder()
+		
+		handlers.ErrorRate(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("routes %s: expected status 400, got %d", routes, w.Code)
+		}
+	}
+}
+
+func TestToggleHandlers_Latency_ValidRequest(t *testing.T) {
+	mockLatency := &mockLatencyToggle{}
+	handlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, mockLatency, &mockMemoryToggle{})
+	
+	req := httptest.NewRequest("POST", "/api/v1/toggles/latency", strings.NewReader(`{"enabled": true, "min_ms": 100, "max_ms": 300}`))
+	w := httptest.NewRecorder()
+	
+	handlers.Latency(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	
+	if !mockLatency.enabled || mockLatency.minMs != 100 || mockLatency.maxMs != 300 {
+		t.Errorf("Expected toggle to be configured, got %+v", mockLatency)
+	}
+}
+
+func TestToggleHandlers_Latency_InvalidRequest(t *testing.T) {
+	handlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, &mockLatencyToggle{}, &mockMemoryToggle{})
+	
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid JSON", body: `{"enabled": true,`},
+		{name: "negative min", body: `{"enabled": true, "min_ms": -1, "max_ms": 100}`},
+		{name: "min above max", body: `{"enabled": true, "min_ms": 200, "max_ms": 100}`},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/toggles/latency", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			
+			handlers.Latency(w, req)
+			
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestToggleHandlers_LatencyRamp(t *testing.T) {
+	mockLatency := &mockLatencyToggle{}
+	handlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, mockLatency, &mockMemoryToggle{})
+	
+	req := httptest.NewRequest("POST", "/api/v1/chaos/latency-ramp", strings.NewReader(`{"start_ms": 50, "end_ms": 800, "duration_s": 120}`))
+	w := httptest.NewRecorder()
+	
+	handlers.LatencyRamp(w, req)
+	
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", w.Code)
+	}
+	
+	if mockLatency.rampStartMs != 50 || mockLatency.rampEndMs != 800 || mockLatency.rampDuration != 120*time.Second {
+		t.Errorf("Expected ramp 50-800ms over 120s, got %+v", mockLatency)
+	}
+}
+
+func TestToggleHandlers_LatencyRamp_InvalidRequest(t *testing.T) {
+	handlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, &mockLatencyToggle{}, &mockMemoryToggle{})
+	
+	for _, body := range []string{
+		`{"start_ms": 50,`,
+		`{"start_ms": -1, "end_ms": 800, "duration_s": 120}`,
+		`{"start_ms": 50, "end_ms": 800, "duration_s": 0}`,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/chaos/latency-ramp", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		handlers.LatencyRamp(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestToggleHandlers_Memory(t *testing.T) {
+	mockMemory := &mockMemoryToggle{}
+	handlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, &mockLatencyToggle{}, mockMemory)
+	
+	req := httptest.NewRequest("POST", "/api/v1/toggles/memory", strings.NewReader(`{"enabled": true, "megabytes": 256}`))
+	w := httptest.NewRecorder()
+	
+	handlers.Memory(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	
+	if !mockMemory.enabled || mockMemory.megabytes != 256 {
+		t.Errorf("Expected toggle to be configured, got %+v", mockMemory)
+	}
+}
+
+func TestToggleHandlers_Memory_InvalidRequest(t *testing.T) {
+	mockMemory := &mockMemoryToggle{}
+	handlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, &mockLatencyToggle{}, mockMemory)
+	
+	for _, body := range []string{
+		`{"enabled": true,`,
+		`{"enabled": true, "megabytes": -1}`,
+		`{"enabled": true, "megabytes": 2049}`,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/toggles/memory", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		handlers.Memory(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+	
+	if mockMemory.enabled {
+		t.Error("Expected toggle to be unchanged")
+	}
+}
+
+func TestAdminHandlers_RotateToken(t *testing.T) {
+	logger := zap.NewNop()
+	tokens := NewTokenStore("old-token")
+	handlers := NewAdminHandlers(logger, tokens)
+	
+	req := httptest.NewRequest("POST", "/api/v1/admin/token", strings.NewReader(`{"token": "new-token"}`))
+	w := httptest.NewRecorder()
+	
+	handlers.RotateToken(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	
+	if !tokens.Valid("new-token") || tokens.Valid("old-token") {
+		t.Errorf("Expected token to be rotated, got %v", tokens.Get())
+	}
+	
+	if strings.Contains(w.Body.String(), "new-token") {
+		t.Error("Response must not echo the new token")
+	}
+}
+
+func TestAdminHandlers_RotateToken_Invalid(t *testing.T) {
+	logger := zap.NewNop()
+	tokens := NewTokenStore("old-token")
+	handlers := NewAdminHandlers(logger, tokens)
+	
+	for _, body := range []string{`{"token": ""}`, `{"token": "   "}`, `invalid json`} {
+		req := httptest.NewRequest("POST", "/api/v1/admin/token", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		handlers.RotateToken(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+	
+	if !tokens.Valid("old-token") {
+		t.Errorf("Expected token to be unchanged, got %v", tokens.Get())
+	}
+}
+
+func TestLogLevelHandler_Invalid(t *testing.T) {
+	levels := logging.NewLevels(zapcore.InfoLevel)
+	handler := LogLevelHandler(zap.NewNop(), levels)
+	
+	for _, body := range []string{
+		`{"component": "http", "level": "verbose"}`,
+		`{"component": "", "level": "debug"}`,
+		`invalid json`,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/loglevel", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		handler(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+	
+	if levels.Level("http") != zapcore.InfoLevel {
+		t.Errorf("Expected the http level to be unchanged, got %v", levels.Level("http"))
+	}
+}
+
 // Mock toggle interface for testing
 type mockToggleInterface struct {
 	enabled    bool
 	rate       float64
 	statusCode int
+	routes     []string
 }
 
-func (m *mockToggleInterface) SetConfig(enabled bool, rate float64, statusCode int) {
+func (m *mockToggleInterface) SetConfig(enabled bool, rate float64, statusCode int, routes ...string) {
 	m.enabled = enabled
 	m.rate = rate
 	m.statusCode = statusCode
+	m.routes = routes
 }
 
 func (m *mockToggleInterface) GetConfig() (bool, float64, int) {
 	return m.enabled, m.rate, m.statusCode
-}
\ No newline at end of file
+}
+
+func (m *mockToggleInterface) GetRoutes() []string {
+	return m.routes
+}
+
+// mockLatencyToggle is a mock implementation of the latency toggle interface
+type mockLatencyToggle struct {
+	enabled bool
+	minMs   int
+	maxMs   int
+	
+	// Arguments of the last StartRamp call
+	rampStartMs  int
+	rampEndMs    int
+	rampDuration time.Duration
+}
+
+func (m *mockLatencyToggle) SetConfig(enabled bool, minMs, maxMs int) {
+	m.enabled = enabled
+	m.minMs = minMs
+	m.maxMs = maxMs
+}
+
+func (m *mockLatencyToggle) GetConfig() (bool, int, int) {
+	return m.enabled, m.minMs, m.maxMs
+}
+
+func (m *mockLatencyToggle) StartRamp(startMs, endMs int, duration, interval time.Duration) {
+	m.rampStartMs = startMs
+	m.rampEndMs = endMs
+	m.rampDuration = duration
+}
+
+// mockMemoryToggle is a mock implementation of the memory toggle interface
+type mockMemoryToggle struct {
+	enabled   bool
+	megabytes int
+}
+
+func (m *mockMemoryToggle) SetConfig(enabled bool, megabytes int) {
+	m.enabled = enabled
+	m.megabytes = megabytes
+}
+
+func (m *mockMemoryToggle) GetConfig() (bool, int) {
+	return m.enabled, m.megabytes
+}
+
+func TestAdminHandlers_CPUProfile(t *testing.T) {
+	handlers := NewAdminHandlers(zap.NewNop(), NewTokenStore("test-token"))
+	
+	req := httptest.NewRequest("GET", "/api/v1/profile/cpu?seconds=1", nil)
+	w := httptest.NewRecorder()
+	
+	handlers.CPUProfile(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	
+	if w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("Expected Content-Type 'application/octet-stream', got '%s'", w.Header().Get("Content-Type"))
+	}
+	
+	if w.Body.Len() == 0 {
+		t.Error("Expected a non-empty profile")
+	}
+}
+
+func TestAdminHandlers_CPUProfile_InvalidSeconds(t *testing.T) {
+	handlers := NewAdminHandlers(zap.NewNop(), NewTokenStore("test-token"))
+	
+	for _, seconds := range []string{"0", "61", "-1", "abc"} {
+		req := httptest.NewRequest("GET", "/api/v1/profile/cpu?seconds="+seconds, nil)
+		w := httptest.NewRecorder()
+		
+		handlers.CPUProfile(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("seconds=%s: expected status %d, got %d", seconds, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestHandlers_RequestIDInResponses(t *testing.T) {
+	apiHandlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	toggleHandlers := NewToggleHandlers(zap.NewNop(), &mockToggleInterface{}, &mockLatencyToggle{}, &mockMemoryToggle{})
+	healthHandlers := NewHealthHandlers(health.NewChecker())
+	adminHandlers := NewAdminHandlers(zap.NewNop(), NewTokenStore("test-token"))
+	logLevel := LogLevelHandler(zap.NewNop(), logging.NewLevels(zapcore.InfoLevel))
+	
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    string
+		status  int
+	}{
+		{"ping", apiHandlers.Ping, "GET", "/api/v1/ping", "", http.StatusOK},
+		{"echo error", apiHandlers.Echo, "GET", "/api/v1/echo?format=xml", "", http.StatusBadRequest},
+		{"work", apiHandlers.Work, "GET", "/api/v1/work?ms=0&jitter=0", "", http.StatusOK},
+		{"work error", apiHandlers.Work, "GET", "/api/v1/work?mode=idle", "", http.StatusBadRequest},
+		{"error rate", toggleHandlers.ErrorRate, "POST", "/api/v1/toggles/error-rate", `{"enabled":false,"rate":0,"status_code":500}`, http.StatusOK},
+		{"error rate error", toggleHandlers.ErrorRate, "POST", "/api/v1/toggles/error-rate", `{"rate":2}`, http.StatusBadRequest},
+		{"get error rate", toggleHandlers.GetErrorRate, "GET", "/api/v1/toggles/error-rate", "", http.StatusOK},
+		{"latency", toggleHandlers.Latency, "POST", "/api/v1/toggles/latency", `{"enabled":false}`, http.StatusOK},
+		{"latency error", toggleHandlers.Latency, "POST", "/api/v1/toggles/latency", `not json`, http.StatusBadRequest},
+		{"memory", toggleHandlers.Memory, "POST", "/api/v1/toggles/memory", `{"enabled":false}`, http.StatusOK},
+		{"latency ramp error", toggleHandlers.LatencyRamp, "POST", "/api/v1/chaos/latency-ramp", `{"duration_s":0}`, http.StatusBadRequest},
+		{"readiness", healthHandlers.ToggleReadiness, "POST", "/api/v1/toggles/readiness", `{"force_failure":false}`, http.StatusOK},
+		{"deadlock error", healthHandlers.ToggleDeadlock, "POST", "/api/v1/toggles/deadlock", `{`, http.StatusBadRequest},
+		{"rotate token", adminHandlers.RotateToken, "POST", "/api/v1/admin/token", `{"token":"new-token"}`, http.StatusOK},
+		{"rotate token error", adminHandlers.RotateToken, "POST", "/api/v1/admin/token", `{"token":""}`, http.StatusBadRequest},
+		{"cpu profile error", adminHandlers.CPUProfile, "GET", "/api/v1/profile/cpu?seconds=0", "", http.StatusBadRequest},
+		{"log level", logLevel, "POST", "/api/v1/loglevel", `{"component":"http","level":"debug"}`, http.StatusOK},
+		{"log level error", logLevel, "POST", "/api/v1/loglevel", `{"component":"http","level":"loud"}`, http.StatusBadRequest},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.RequestID(RequestIDMiddleware(tt.handler))
+			
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected a JSON response, got %q", w.Header().Get("Content-Type"))
+			}
+			
+			var response map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			
+			headerID := w.Header().Get("X-Request-ID")
+			if headerID == "" {
+				t.Fatal("Expected an X-Request-ID response header")
+			}
+			if response["request_id"] != headerID {
+				t.Errorf("Expected request_id %q to match X-Request-ID, got %v", headerID, response["request_id"])
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"monitoring-dashboard-automation/internal/metrics"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// IdempotencyKeyHeader identifies retries of the same client request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotentBodyBytes caps the response bodies kept for replay; larger
+// responses are not cached
+const maxIdempotentBodyBytes = 64 * 1024
+
+// idempotentResponse is a response recorded for replay
+type idempotentResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore keeps responses by idempotency key until their TTL passes
+type idempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	responses map[string]idempotentResponse
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:       ttl,
+		responses: make(map[string]idempotentResponse),
+		now:       time.Now,
+	}
+}
+
+// get returns the unexpired response recorded for key
+func (s *idempotencyStore) get(key string) (idempotentResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.sweep()
+	resp, ok := s.responses[key]
+	if !ok || !s.now().Before(resp.expires) {
+		return idempotentResponse{}, false
+	}
+	return resp, true
+}
+
+// put records the response for key for the store's TTL
+func (s *idempotencyStore) put(key string, resp idempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	resp.expires = s.now().Add(s.ttl)
+	s.responses[key] = resp
+}
+
+// sweep drops expired responses, at most once per TTL; callers must hold s.mu
+func (s *idempotencyStore) sweep() {
+	now := s.now()
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	
+	for key, resp := range s.responses {
+		if !now.Before(resp.expires) {
+			delete(s.responses, key)
+		}
+	}
+}
+
+// hijackTracker records whether the handler took over the connection, in
+// which case nothing it wrote can be replayed
+type hijackTracker struct {
+	middleware.WrapResponseWriter
+	hijacked bool
+}
+
+// Flush passes through to the wrapped writer so streaming responses work
+func (t *hijackTracker) Flush() {
+	if flusher, ok := t.WrapResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack passes through to the wrapped writer, noting that it happened
+func (t *hijackTracker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := t.WrapResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	t.hijacked = true
+	return hijacker.Hijack()
+}
+
+// idempotencyKey scopes an Idempotency-Key to the request's method, path and
+// query and to its caller, the client address (see clientIP for trustProxy)
+// plus a digest of its credentials, so one client cannot replay another's
+// response by reusing its key
+func idempotencyKey(r *http.Request, key string, trustProxy bool) string {
+	credentials := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return strings.Join([]string{
+		r.Method,
+		r.URL.RequestURI(),
+		clientIP(r, trustProxy),
+		hex.EncodeToString(credentials[:]),
+		key,
+	}, " ")
+}
+
+// IdempotencyMiddleware replays the recorded response when a caller repeats
+// an Idempotency-Key seen within ttl for the same request, counting the
+// replay. Only successful responses are recorded, so errors are retried for
+// real. Concurrent first attempts are not deduplicated.
+func IdempotencyMiddleware(metricsRegistry *metrics.Registry, ttl time.Duration, trustProxy bool) func(next http.Handler) http.Handler {
+	store := newIdempotencyStore(ttl)
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = idempotencyKey(r, key, trustProxy)
+			
+			if resp, ok := store.get(key); ok {
+				metricsRegistry.IncIdempotentReplay(getRoutePattern(r))
+				
+				if resp.contentType != "" {
+					w.Header().Set("Content-Type", resp.contentType)
+				}
+				w.WriteHeader(resp.status)
+				w.Write(resp.body)
+				return
+			}
+			
+			body := &cappedBuffer{limit: maxIdempotentBodyBytes + 1}
+			ww := &hijackTracker{WrapResponseWriter: middleware.NewWrapResponseWriter(w, r.ProtoMajor)}
+			ww.Tee(body)
+			
+			next.ServeHTTP(ww, r)
+			
+			// Only complete, successful responses are replayed: nothing was
+			// written when the status is 0, a hijacked connection bypasses
+			// ww, and errors (auth failures, injected faults) must not stick
+			if ww.Status() < 100 || ww.Status() >= http.StatusBadRequest || ww.hijacked || body.Len() > maxIdempotentBodyBytes {
+				return
+			}
+			store.put(key, idempotentResponse{
+				status:      ww.Status(),
+				contentType: ww.Header().Get("Content-Type"),
+				body:        bytes.Clone(body.Bytes()),
+			})
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitoring-dashboard-automation/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func TestIdempotencyMiddleware_Replay(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	calls := 0
+	// Routed through chi so the replay is labelled with the route pattern
+	handler := chi.NewRouter()
+	handler.With(IdempotencyMiddleware(metricsRegistry, time.Minute, false)).Post("/api/v1/work", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	})
+	
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api/v1/work", nil)
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		w := httptest.NewRecorder()
+		
+		handler.ServeHTTP(w, req)
+		
+		if w.Code != http.StatusCreated {
+			t.Errorf("Request %d: expected status %d, got %d", i, http.StatusCreated, w.Code)
+		}
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Request %d: expected JSON content type, got %q", i, w.Header().Get("Content-Type"))
+		}
+		bodies = append(bodies, w.Body.String())
+	}
+	
+	if calls != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", calls)
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("Expected the replay to return the same body, got %q and %q", bodies[0], bodies[1])
+	}
+	
+	w := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	
+	if !strings.Contains(w.Body.String(), `http_idempotent_replays_total{route="/api/v1/work"} 1`) {
+		t.Error("Expected http_idempotent_replays_total to count one replay")
+	}
+}
+
+func TestIdempotencyMiddleware_DistinctKeys(t *testing.T) {
+	calls := 0
+	handler := IdempotencyMiddleware(metrics.NewRegistry(), time.Minute, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	// Requests without a key, and with different keys, always reach the handler
+	for _, key := range []string{"", "", "a", "b"} {
+		req := httptest.NewRequest("POST", "/api/v1/work", nil)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	
+	if calls != 4 {
+		t.Errorf("Expected the handler to run 4 times, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyMiddleware_EmptyResponseNotRecorded(t *testing.T) {
+	calls := 0
+	handler := IdempotencyMiddleware(metrics.NewRegistry(), time.Minute, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	
+	// A handler that writes nothing must not leave a status 0 for the retry to replay
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/api/v1/reset", nil)
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		w := httptest.NewRecorder()
+		
+		handler.ServeHTTP(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Errorf("Request %d: expected the implicit status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+	
+	if calls != 2 {
+		t.Errorf("Expected the handler to run for both requests, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyMiddleware_HijackedResponseNotRecorded(t *testing.T) {
+	var calls atomic.Int32
+	handlers := NewAPIHandlers(zap.NewNop(), metrics.NewRegistry(), WorkConfig{})
+	server := httptest.NewServer(IdempotencyMiddleware(metrics.NewRegistry(), time.Minute, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handlers.Reset(w, r)
+	})))
+	defer server.Close()
+	
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", server.URL+"/api/v1/reset", nil)
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		
+		resp, err := server.Client().Do(req)
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("Request %d: expected the connection to be reset, got status %d", i, resp.StatusCode)
+		}
+	}
+	
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected the handler to run for both requests, ran %d times", n)
+	}
+}
+
+func TestIdempotencyMiddleware_ScopedToCallerAndRequest(t *testing.T) {
+	calls := 0
+	handler := IdempotencyMiddleware(metrics.NewRegistry(), time.Minute, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	// The same key from other credentials, another client or for another
+	// query is a different request
+	requests := []struct {
+		target        string
+		remoteAddr    string
+		authorization string
+	}{
+		{"/api/v1/work?ms=10", "10.0.0.1:1234", "Bearer a"},
+		{"/api/v1/work?ms=10", "10.0.0.1:1234", ""},
+		{"/api/v1/work?ms=10", "10.0.0.1:1234", "Bearer b"},
+		{"/api/v1/work?ms=10", "10.0.0.2:1234", "Bearer a"},
+		{"/api/v1/work?ms=20", "10.0.0.1:1234", "Bearer a"},
+	}
+	for _, request := range requests {
+		req := httptest.NewRequest("POST", request.target, nil)
+		req.RemoteAddr = request.remoteAddr
+		if request.authorization != "" {
+			req.Header.Set("Authorization", request.authorization)
+		}
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	
+	if calls != len(requests) {
+		t.Errorf("Expected the handler to run %d times, ran %d times", len(requests), calls)
+	}
+}
+
+func TestIdempotencyMiddleware_ErrorResponseNotRecorded(t *testing.T) {
+	calls := 0
+	handler := IdempotencyMiddleware(metrics.NewRegistry(), time.Minute, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	// A failed first attempt is retried for real rather than replayed
+	codes := make([]int, 2)
+	for i := range codes {
+		req := httptest.NewRequest("POST", "/api/v1/work", nil)
+		req.Header.Set(IdempotencyKeyHeader, "abc")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		codes[i] = w.Code
+	}
+	
+	if codes[0] != http.StatusServiceUnavailable || codes[1] != http.StatusOK {
+		t.Errorf("Expected statuses [503 200], got %v", codes)
+	}
+}
+
+func TestIdempotencyStore_Expiry(t *testing.T) {
+	now := time.Now()
+	store := newIdempotencyStore(time.Minute)
+	store.now = func() time.Time { return now }
+	
+	store.put("key", idempotentResponse{status: http.StatusOK})
+	
+	if _, ok := store.get("key"); !ok {
+		t.Fatal("Expected the response to be stored")
+	}
+	
+	now = now.Add(time.Minute)
+	if _, ok := store.get("key"); ok {
+		t.Error("Expected the response to expire after the TTL")
+	}
+	if len(store.responses) != 0 {
+		t.Errorf("Expected expired responses to be swept, %d left", len(store.responses))
+	}
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net"
+	"net/http"
+)
+
+// IPAllowlistMiddleware rejects requests from outside allowedCIDRs with 403.
+// The client IP is resolved by clientIP. Entries that are not valid CIDR
+// ranges are ignored.
+func IPAllowlistMiddleware(allowedCIDRs []string, trustProxy bool) func(next http.Handler) http.Handler {
+	var nets []*net.IPNet
+	for _, entry := range allowedCIDRs {
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := net.ParseIP(clientIP(r, trustProxy)); ip != nil {
+				for _, ipNet := range nets {
+					if ipNet.Contains(ip) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+			
+			writeJSONError(w, r, http.StatusForbidden, "Source address not allowed")
+		})
+	}
+}
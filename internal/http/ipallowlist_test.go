@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPAllowlistMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		trustProxy   bool
+		remoteAddr   string
+		forwardedFor string
+		expectedCode int
+	}{
+		{name: "allowed address", remoteAddr: "10.1.2.3:4000", expectedCode: http.StatusOK},
+		{name: "allowed IPv6 address", remoteAddr: "[fd00::5]:4000", expectedCode: http.StatusOK},
+		{name: "disallowed address", remoteAddr: "192.168.1.10:4000", expectedCode: http.StatusForbidden},
+		{name: "forwarded header ignored without proxy trust", remoteAddr: "192.168.1.10:4000", forwardedFor: "10.1.2.3", expectedCode: http.StatusForbidden},
+		{name: "forwarded client allowed behind trusted proxy", trustProxy: true, remoteAddr: "192.168.1.10:4000", forwardedFor: "10.1.2.3, 192.168.1.10", expectedCode: http.StatusOK},
+		{name: "forwarded client disallowed behind trusted proxy", trustProxy: true, remoteAddr: "10.1.2.3:4000", forwardedFor: "203.0.113.7", expectedCode: http.StatusForbidden},
+		{name: "trusted proxy without forwarded header", trustProxy: true, remoteAddr: "10.1.2.3:4000", expectedCode: http.StatusOK},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := IPAllowlistMiddleware([]string{"10.0.0.0/8", "fd00::/8"}, tt.trustProxy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			
+			req := httptest.NewRequest("POST", "/api/v1/toggles/latency", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			
+			handler.ServeHTTP(w, req)
+			
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
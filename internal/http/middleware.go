@@ -1,15 +1,35 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/rand"
 	"net/http"
 	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"monitoring-dashboard-automation/internal/audit"
+	"monitoring-dashboard-automation/internal/httpjson"
 	"monitoring-dashboard-automation/internal/metrics"
+	"monitoring-dashboard-automation/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +38,50 @@ type contextKey string
 
 const RequestIDKey contextKey = "requestID"
 
+// requestIDFromContext returns the request ID set by RequestIDMiddleware, or
+// "" outside of it
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
+// TokenNameKey is the context key for the name of the authenticated admin token
+const TokenNameKey contextKey = "tokenName"
+
+// InjectionKey is the context key for the per-request injection record
+const InjectionKey contextKey = "injection"
+
+// InjectionInfo records what the injection middleware did to a request
+type InjectionInfo struct {
+	Error     bool  `json:"error"`
+	LatencyMs int64 `json:"latency_ms"`
+
+	// latency is the exact injected delay, subtracted from the request
+	// duration for http_handler_duration_seconds
+	latency time.Duration
+}
+
+// withInjectionInfo returns the request's injection record, attaching a new
+// one to the request context if no injection middleware has done so yet
+func withInjectionInfo(r *http.Request) (*http.Request, *InjectionInfo) {
+	if info, ok := r.Context().Value(InjectionKey).(*InjectionInfo); ok {
+		return r, info
+	}
+	info := &InjectionInfo{}
+	return r.WithContext(context.WithValue(r.Context(), InjectionKey, info)), info
+}
+
+// adminTokenName identifies which admin token was presented in audit entries
+// without exposing it: the first 8 hex digits of its SHA-256 digest, so the
+// tokens in ADMIN_TOKENS can be told apart
+func adminTokenName(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return "admin-" + hex.EncodeToString(digest[:4])
+}
+
+// maxAuditBodyBytes caps how much of a request body is captured for auditing
+const maxAuditBodyBytes = 64 * 1024
+
 // RequestIDMiddleware generates and adds a unique request ID to each request
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -30,8 +94,42 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// LoggingMiddleware logs HTTP requests with structured logging
-func LoggingMiddleware(logger *zap.Logger) func(next http.Handler) http.Handler {
+// JSONEncodeErrorMiddleware logs JSON response bodies that fail to encode and
+// counts them in http_json_encode_errors_total by route pattern
+func JSONEncodeErrorMiddleware(logger *zap.Logger, metricsRegistry *metrics.Registry) func(next http.Handler) http.Handler {
+	report := func(r *http.Request, err error) {
+		endpoint := getRoutePattern(r)
+		metricsRegistry.IncJSONEncodeError(endpoint)
+		logger.Warn("Failed to encode JSON response",
+			zap.String("endpoint", endpoint),
+			zap.String("request_id", requestIDFromContext(r.Context())),
+			zap.Error(err))
+	}
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(httpjson.WithErrorReporter(r.Context(), report)))
+		})
+	}
+}
+
+// AccessLogConfig selects what LoggingMiddleware logs per request
+type AccessLogConfig struct {
+	// LogRequestStart adds a "Request started" line before each request
+	LogRequestStart bool
+	
+	// Fields lists optional fields added to the "Request completed" line:
+	// query, bytes, remote_addr, client_ip and user_agent
+	Fields []string
+	
+	// TrustProxy resolves client_ip from forwarding headers (see clientIP)
+	TrustProxy bool
+}
+
+// LoggingMiddleware logs HTTP requests with structured logging. Every request
+// gets a "Request completed" line with request_id, method, path, status and
+// duration plus the configured optional fields.
+func LoggingMiddleware(logger *zap.Logger, accessLog AccessLogConfig) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -40,27 +138,49 @@ func LoggingMiddleware(logger *zap.Logger) func(next http.Handler) http.Handler
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 			
 			// Get request ID from context
-			requestID, _ := r.Context().Value(RequestIDKey).(string)
+			requestID := requestIDFromContext(r.Context())
+			
+			// Correlate log lines with the request's trace when tracing is enabled
+			logger := logger
+			if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+				logger = logger.With(zap.String("trace_id", span.TraceID().String()))
+			}
 			
 			// Log request start
-			logger.Info("Request started",
-				zap.String("method", r.Method),
-				zap.String("path", r.URL.Path),
-				zap.String("remote_addr", r.RemoteAddr),
-				zap.String("user_agent", r.UserAgent()),
-				zap.String("request_id", requestID),
-			)
+			if accessLog.LogRequestStart {
+				logger.Info("Request started",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.String("user_agent", r.UserAgent()),
+					zap.String("request_id", requestID),
+				)
+			}
 			
 			defer func() {
 				// Log request completion
-				logger.Info("Request completed",
+				fields := []zap.Field{
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
 					zap.Int("status", ww.Status()),
-					zap.Int("bytes", ww.BytesWritten()),
 					zap.Duration("duration", time.Since(start)),
 					zap.String("request_id", requestID),
-				)
+				}
+				for _, field := range accessLog.Fields {
+					switch field {
+					case "query":
+						fields = append(fields, zap.String("query", r.URL.RawQuery))
+					case "bytes":
+						fields = append(fields, zap.Int("bytes", ww.BytesWritten()))
+					case "remote_addr":
+						fields = append(fields, zap.String("remote_addr", r.RemoteAddr))
+					case "client_ip":
+						fields = append(fields, zap.String("client_ip", clientIP(r, accessLog.TrustProxy)))
+					case "user_agent":
+						fields = append(fields, zap.String("user_agent", r.UserAgent()))
+					}
+				}
+				logger.Info("Request completed", fields...)
 			}()
 			
 			next.ServeHTTP(ww, r)
@@ -68,14 +188,59 @@ func LoggingMiddleware(logger *zap.Logger) func(next http.Handler) http.Handler
 	}
 }
 
-// PanicRecoveryMiddleware recovers from panics and logs stack traces
-func PanicRecoveryMiddleware(logger *zap.Logger) func(next http.Handler) http.Handler {
+// Response headers naming the request's trace and server span, for debugging
+// without a tracing backend
+const (
+	TraceIDHeader = "X-Trace-ID"
+	SpanIDHeader  = "X-Span-ID"
+)
+
+// TracingMiddleware starts a server span per request, continuing the trace from
+// an incoming traceparent header or starting a new one, and stores the span in
+// the request context for LoggingMiddleware and handlers. The trace and span
+// IDs are echoed in the response headers.
+func TracingMiddleware(tracer trace.Tracer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// An invalid or missing header starts a new trace
+			ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer))
+			
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			tracing.Propagator.Inject(ctx, propagation.HeaderCarrier(ww.Header()))
+			ww.Header().Set(TraceIDHeader, span.SpanContext().TraceID().String())
+			ww.Header().Set(SpanIDHeader, span.SpanContext().SpanID().String())
+			
+			defer func() {
+				// The route pattern is only known once chi has routed the request
+				route := getRoutePattern(r)
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(
+					attribute.String("http.method", r.Method),
+					attribute.String("http.route", route),
+					attribute.Int("http.status_code", ww.Status()),
+				)
+				if ww.Status() >= 500 {
+					span.SetStatus(codes.Error, http.StatusText(ww.Status()))
+				}
+				span.End()
+			}()
+			
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// PanicRecoveryMiddleware recovers from panics, logs stack traces and counts
+// them in panics_recovered_total. It must run inside the logging and
+// Prometheus middleware so the 500 it answers is logged and counted.
+func PanicRecoveryMiddleware(logger *zap.Logger, metricsRegistry *metrics.Registry) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
 					// Get request ID from context
-					requestID, _ := r.Context().Value(RequestIDKey).(string)
+					requestID := requestIDFromContext(r.Context())
 					
 					// Log the panic with stack trace
 					logger.Error("Panic recovered",
@@ -85,9 +250,10 @@ func PanicRecoveryMiddleware(logger *zap.Logger) func(next http.Handler) http.Ha
 						zap.String("request_id", requestID),
 						zap.String("stack", string(debug.Stack())),
 					)
+					metricsRegistry.IncPanic()
 					
 					// Return 500 Internal Server Error
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					writeJSONError(w, r, http.StatusInternalServerError, "Internal Server Error")
 				}
 			}()
 			
@@ -105,57 +271,427 @@ func PrometheusMiddleware(metricsRegistry *metrics.Registry) func(next http.Hand
 			// Create a response writer wrapper to capture status code
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 			
+			// Share the injection record so latency injected further down
+			// the chain can be excluded from the handler duration
+			r, info := withInjectionInfo(r)
+			
 			// Process the request
 			next.ServeHTTP(ww, r)
 			
 			// Record metrics after request completion
 			duration := time.Since(start)
+			handlerDuration := duration - info.latency
+			if handlerDuration < 0 {
+				handlerDuration = 0
+			}
 			
 			// Get the route pattern from chi router context
 			route := getRoutePattern(r)
 			
 			// Record the HTTP request metrics
 			metricsRegistry.RecordHTTPRequest(r.Method, route, ww.Status(), duration)
+			metricsRegistry.RecordHTTPHandlerDuration(r.Method, route, handlerDuration)
+			metricsRegistry.RecordHTTPSizes(route, int(r.ContentLength), ww.BytesWritten())
+			metricsRegistry.RecordHTTPClient(classifyUserAgent(r.UserAgent()))
 		})
 	}
 }
 
-// BearerTokenAuthMiddleware validates bearer token for admin routes
-func BearerTokenAuthMiddleware(adminToken string) func(next http.Handler) http.Handler {
+// userAgentCategories maps user-agent prefixes to a small, fixed set of
+// client labels so the metric cardinality stays bounded
+var userAgentCategories = []struct {
+	prefix   string
+	category string
+}{
+	{"Prometheus/", "prometheus"},
+	{"curl/", "curl"},
+	{"Mozilla/", "browser"},
+}
+
+// classifyUserAgent buckets a user-agent into browser, curl, prometheus or other
+func classifyUserAgent(userAgent string) string {
+	for _, c := range userAgentCategories {
+		if strings.HasPrefix(userAgent, c.prefix) {
+			return c.category
+		}
+	}
+	return "other"
+}
+
+// TokenStore holds the valid admin tokens so they can be rotated at runtime.
+// More than one token may be valid at once, so clients can move to a new
+// token before the old one is retired.
+type TokenStore struct {
+	tokens atomic.Value
+}
+
+// NewTokenStore creates a token store holding the given admin tokens
+func NewTokenStore(tokens ...string) *TokenStore {
+	store := &TokenStore{}
+	store.Set(tokens...)
+	return store
+}
+
+// Get returns the currently valid admin tokens
+func (s *TokenStore) Get() []string {
+	return s.tokens.Load().([]string)
+}
+
+// Set replaces the valid admin tokens; subsequent requests must present one of the new ones
+func (s *TokenStore) Set(tokens ...string) {
+	s.tokens.Store(append([]string(nil), tokens...))
+}
+
+// Valid reports whether token matches any of the valid admin tokens.
+// Every candidate is compared in constant time so the result does not leak
+// how much of a token matched or which candidate did. SHA-256 digests are
+// compared rather than the tokens themselves because ConstantTimeCompare
+// returns early when the lengths differ, which would leak the token length.
+func (s *TokenStore) Valid(token string) bool {
+	presented := sha256.Sum256([]byte(token))
+	
+	valid := 0
+	for _, candidate := range s.Get() {
+		expected := sha256.Sum256([]byte(candidate))
+		valid |= subtle.ConstantTimeCompare(presented[:], expected[:])
+	}
+	return valid == 1
+}
+
+// BearerTokenAuthMiddleware validates bearer token for admin routes.
+// The presented token may match any token in the store, which is read on every
+// request so rotation takes effect immediately.
+func BearerTokenAuthMiddleware(tokens *TokenStore) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				writeJSONError(w, r, http.StatusUnauthorized, "Authorization header required")
 				return
 			}
 			
 			// Check if it starts with "Bearer "
 			const bearerPrefix = "Bearer "
 			if len(authHeader) < len(bearerPrefix) || authHeader[:len(bearerPrefix)] != bearerPrefix {
-				http.Error(w, "Invalid authorization format. Expected 'Bearer <token>'", http.StatusUnauthorized)
+				writeJSONError(w, r, http.StatusUnauthorized, "Invalid authorization format. Expected 'Bearer <token>'")
 				return
 			}
 			
 			// Extract token
 			token := authHeader[len(bearerPrefix):]
-			if token != adminToken {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+			if !tokens.Valid(token) {
+				writeJSONError(w, r, http.StatusUnauthorized, "Invalid token")
 				return
 			}
 			
 			// Token is valid, proceed to next handler
+			ctx := context.WithValue(r.Context(), TokenNameKey, adminTokenName(token))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AuditMiddleware records successful admin actions in the audit log.
+// It must run after BearerTokenAuthMiddleware so the token name is known.
+func AuditMiddleware(auditLog *audit.Log) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reads do not change anything and are not audited
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			
+			// Capture the JSON body so it can be recorded as the action parameters
+			var params map[string]interface{}
+			if r.Body != nil {
+				body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBodyBytes))
+				if err == nil {
+					json.Unmarshal(body, &params)
+				}
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			}
+			
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			
+			// Only actions that were actually applied are audited
+			if ww.Status() >= http.StatusBadRequest {
+				return
+			}
+			
+			tokenName, _ := r.Context().Value(TokenNameKey).(string)
+			auditLog.Record(r.Method+" "+getRoutePattern(r), tokenName, params)
+		})
+	}
+}
+
+// TimeoutMiddleware cancels the request context once timeout has passed and
+// answers 504 if the handler gave up without responding; a zero timeout
+// disables the limit. Routes that must stay open, such as SSE streams, are
+// registered without it (see timedRoutes).
+func TimeoutMiddleware(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+			
+			// Handlers that notice the deadline usually answer themselves
+			// (work answers 408); only fill in for those that didn't
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && ww.Status() == 0 {
+				writeJSONError(w, r, http.StatusGatewayTimeout, "Request timed out")
+			}
+		})
+	}
+}
+
+// BodySamplingMiddleware logs a truncated sample of the request and response
+// bodies for a random fraction of requests. JSON bodies have secret-looking
+// fields redacted before they are truncated to maxBytes.
+func BodySamplingMiddleware(logger *zap.Logger, rate float64, maxBytes int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rate <= 0 || rand.Float64() >= rate {
+				next.ServeHTTP(w, r)
+				return
+			}
+			
+			var requestBody []byte
+			if r.Body != nil {
+				requestBody, _ = io.ReadAll(io.LimitReader(r.Body, maxAuditBodyBytes))
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), r.Body))
+			}
+			
+			responseBody := &cappedBuffer{limit: maxAuditBodyBytes}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(responseBody)
+			
+			next.ServeHTTP(ww, r)
+			
+			requestID := requestIDFromContext(r.Context())
+			logger.Info("Body sample",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("request_body", sampleBody(requestBody, maxBytes)),
+				zap.String("response_body", sampleBody(responseBody.Bytes(), maxBytes)),
+				zap.String("request_id", requestID),
+			)
+		})
+	}
+}
+
+// cappedBuffer keeps at most limit bytes and silently drops the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// sampleBody redacts secrets from a JSON body and truncates it to maxBytes
+func sampleBody(body []byte, maxBytes int) string {
+	var params map[string]interface{}
+	if json.Unmarshal(body, &params) == nil {
+		if redacted, err := json.Marshal(audit.Redact(params)); err == nil {
+			body = redacted
+		}
+	}
+	
+	if maxBytes >= 0 && len(body) > maxBytes {
+		return string(body[:maxBytes]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// StrictQueryParamsMiddleware rejects requests carrying query parameters
+// outside the allowed set, listing the unknown keys in the 400 response
+func StrictQueryParamsMiddleware(allowed []string) func(next http.Handler) http.Handler {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, key := range allowed {
+		allowedSet[key] = true
+	}
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var unknown []string
+			for key := range r.URL.Query() {
+				if !allowedSet[key] {
+					unknown = append(unknown, key)
+				}
+			}
+			
+			if len(unknown) > 0 {
+				sort.Strings(unknown)
+				writeJSONError(w, r, http.StatusBadRequest, "Unknown query parameters: "+strings.Join(unknown, ", "))
+				return
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxBodyBytesMiddleware rejects request bodies larger than limit bytes with
+// 413. The body is buffered up front so handlers never see a partial body.
+func MaxBodyBytesMiddleware(limit int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeJSONError(w, r, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			
+			if r.Body != nil {
+				// Read one byte past the limit to detect oversized chunked bodies
+				body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+				if err != nil {
+					writeJSONError(w, r, http.StatusBadRequest, "Failed to read request body")
+					return
+				}
+				if int64(len(body)) > limit {
+					writeJSONError(w, r, http.StatusRequestEntityTooLarge, "Request body too large")
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NoStoreMiddleware marks responses as not cacheable, for endpoints such as
+// probes and metrics that must always reflect the current state
+func NoStoreMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MaxURLLengthMiddleware rejects requests whose URL (path and query) is longer
+// than limit characters with 414
+func MaxURLLengthMiddleware(limit int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.URL.String()) > limit {
+				writeJSONError(w, r, http.StatusRequestURITooLong, "Request URL too long")
+				return
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CORS methods and request headers browsers may use against the API
+const (
+	corsAllowedMethods = "GET, POST, OPTIONS"
+	corsAllowedHeaders = "Authorization, Content-Type, Idempotency-Key, X-Inject-Error"
+)
+
+// StreamLimitMiddleware caps the number of streaming (SSE) connections open at
+// once. Connections beyond the limit are rejected with 503 rather than queued,
+// since a stream holds its connection for as long as the client wants.
+func StreamLimitMiddleware(metricsRegistry *metrics.Registry, limit int) func(next http.Handler) http.Handler {
+	var active int64
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt64(&active, 1) > int64(limit) {
+				atomic.AddInt64(&active, -1)
+				w.Header().Set("Retry-After", "1")
+				writeJSONError(w, r, http.StatusServiceUnavailable, "Too many streaming connections")
+				return
+			}
+			metricsRegistry.IncStreamConnections()
+			defer func() {
+				metricsRegistry.DecStreamConnections()
+				atomic.AddInt64(&active, -1)
+			}()
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// StreamOnly applies mw to streaming requests (?stream=true) only, so regular
+// requests to the same route are not counted as streams
+func StreamOnly(mw func(http.Handler) http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		streamed := mw(next)
+		
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if streamRequested(r) {
+				streamed.ServeHTTP(w, r)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// ErrorInjectionMiddleware injects errors based on toggle configuration
-func ErrorInjectionMiddleware(errorToggle interface{}) func(next http.Handler) http.Handler {
+// CORSMiddleware allows browsers on the given origins ("*" for any) to call
+// the API. OPTIONS requests are answered with 204 without reaching the routes.
+func CORSMiddleware(allowedOrigins []string) func(next http.Handler) http.Handler {
+	allowAll := false
+	allowedSet := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowedSet[origin] = true
+	}
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && (allowAll || allowedSet[origin]) {
+				if allowAll {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Add("Vary", "Origin")
+				}
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			}
+			
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ErrorInjectionMiddleware injects errors based on toggle configuration.
+// It must be applied per route (e.g. in a chi Group) so the full route
+// pattern is known when deciding whether the request is in scope.
+func ErrorInjectionMiddleware(errorToggle interface{}, metricsRegistry *metrics.Registry) func(next http.Handler) http.Handler {
 	// Type assertion to get the actual ErrorToggle
 	toggle, ok := errorToggle.(interface {
-		ShouldInjectError() (bool, int)
+		ShouldInjectError(route string) (bool, int)
 	})
 	if !ok {
 		// If type assertion fails, return a no-op middleware
@@ -164,11 +700,21 @@ func ErrorInjectionMiddleware(errorToggle interface{}) func(next http.Handler) h
 		}
 	}
 	
+	window := newInjectionWindow(errorInjectionWindowSize)
+	
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, info := withInjectionInfo(r)
+			
 			// Check if we should inject an error
-			if shouldInject, statusCode := toggle.ShouldInjectError(); shouldInject {
-				http.Error(w, "Injected error for testing", statusCode)
+			route := getRoutePattern(r)
+			shouldInject, statusCode := toggle.ShouldInjectError(route)
+			metricsRegistry.SetErrorInjectionObservedRate(window.record(shouldInject))
+			
+			if shouldInject {
+				info.Error = true
+				metricsRegistry.IncInjectedError(route, statusCode)
+				writeJSONError(w, r, statusCode, "Injected error for testing")
 				return
 			}
 			
@@ -178,6 +724,115 @@ func ErrorInjectionMiddleware(errorToggle interface{}) func(next http.Handler) h
 	}
 }
 
+// LatencyInjectionMiddleware delays requests based on toggle configuration.
+// The delay is cut short if the request context is cancelled.
+func LatencyInjectionMiddleware(latencyToggle interface{}) func(next http.Handler) http.Handler {
+	toggle, ok := latencyToggle.(interface {
+		ShouldDelay() time.Duration
+	})
+	if !ok {
+		// If type assertion fails, return a no-op middleware
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			delay := toggle.ShouldDelay()
+			if delay <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			
+			r, info := withInjectionInfo(r)
+			info.LatencyMs = delay.Milliseconds()
+			info.latency = delay
+			
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				// Client went away or the request timed out while delayed
+				return
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// errorInjectionWindowSize is the number of recent requests the observed
+// injection rate is computed over
+const errorInjectionWindowSize = 1000
+
+// injectionWindow tracks which of the most recent requests had an error injected
+type injectionWindow struct {
+	mu       sync.Mutex
+	outcomes []bool
+	next     int
+	count    int
+	injected int
+}
+
+func newInjectionWindow(size int) *injectionWindow {
+	return &injectionWindow{outcomes: make([]bool, size)}
+}
+
+// record adds an outcome, evicting the oldest once full, and returns the
+// fraction of injected requests in the window
+func (iw *injectionWindow) record(injected bool) float64 {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	
+	if iw.count == len(iw.outcomes) {
+		if iw.outcomes[iw.next] {
+			iw.injected--
+		}
+	} else {
+		iw.count++
+	}
+	
+	iw.outcomes[iw.next] = injected
+	if injected {
+		iw.injected++
+	}
+	iw.next = (iw.next + 1) % len(iw.outcomes)
+	
+	return float64(iw.injected) / float64(iw.count)
+}
+
+// InjectErrorHeader lets a client force an error status for a single request
+const InjectErrorHeader = "X-Inject-Error"
+
+// HeaderErrorInjectionMiddleware responds with the status given in the
+// X-Inject-Error header. Values that are not 4xx/5xx status codes are rejected with 400.
+func HeaderErrorInjectionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(InjectErrorHeader)
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		
+		statusCode, err := strconv.Atoi(value)
+		if err != nil || statusCode < 400 || statusCode > 599 {
+			writeJSONError(w, r, http.StatusBadRequest, InjectErrorHeader+" must be a 4xx or 5xx status code")
+			return
+		}
+		
+		r, info := withInjectionInfo(r)
+		info.Error = true
+		writeJSONError(w, r, statusCode, "Injected error for testing")
+	})
+}
+
+// unmatchedRoute labels requests that matched no route. The raw path is not
+// used so that scans of random paths cannot blow up metric cardinality.
+const unmatchedRoute = "unmatched"
+
 // getRoutePattern extracts the route pattern from chi router context
 func getRoutePattern(r *http.Request) string {
 	// Try to get the route pattern from chi context
@@ -187,6 +842,6 @@ func getRoutePattern(r *http.Request) string {
 		}
 	}
 	
-	// Fallback to the request path if no pattern is found
-	return r.URL.Path
+	// Fall back to a fixed label if no pattern is found
+	return unmatchedRoute
 }
\ No newline at end of file
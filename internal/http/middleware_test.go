@@ -1,14 +1,28 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"monitoring-dashboard-automation/internal/health"
 	"monitoring-dashboard-automation/internal/metrics"
+	"monitoring-dashboard-automation/internal/toggles"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestPrometheusMiddleware(t *testing.T) {
@@ -64,6 +78,107 @@ func TestPrometheusMiddleware(t *testing.T) {
 	}
 }
 
+func TestPrometheusMiddleware_Sizes(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	r := chi.NewRouter()
+	r.Use(PrometheusMiddleware(metricsRegistry))
+	r.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(strings.Repeat("x", 2048)))
+	})
+	
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat("y", 512)))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	
+	metricsW := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(metricsW, httptest.NewRequest("GET", "/metrics", nil))
+	metricsBody := metricsW.Body.String()
+	
+	for _, expected := range []string{
+		`http_request_size_bytes_sum{route="/upload"} 512`,
+		`http_request_size_bytes_bucket{route="/upload",le="1000"} 1`,
+		`http_response_size_bytes_sum{route="/upload"} 2048`,
+		`http_response_size_bytes_bucket{route="/upload",le="1000"} 0`,
+		`http_response_size_bytes_bucket{route="/upload",le="10000"} 1`,
+	} {
+		if !strings.Contains(metricsBody, expected) {
+			t.Errorf("Expected %s in metrics output", expected)
+		}
+	}
+}
+
+func TestPrometheusMiddleware_ClientCategories(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	r := chi.NewRouter()
+	r.Use(PrometheusMiddleware(metricsRegistry))
+	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	
+	for _, userAgent := range []string{"Prometheus/2.47.0", "curl/8.4.0", "curl/7.88.1"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("User-Agent", userAgent)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	
+	metricsW := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(metricsW, httptest.NewRequest("GET", "/metrics", nil))
+	metricsBody := metricsW.Body.String()
+	
+	if !strings.Contains(metricsBody, `http_requests_by_client_total{client="prometheus"} 1`) {
+		t.Error("Expected Prometheus user-agent to be classified as prometheus")
+	}
+	
+	if !strings.Contains(metricsBody, `http_requests_by_client_total{client="curl"} 2`) {
+		t.Error("Expected curl user-agents to be classified as curl")
+	}
+}
+
+func TestPrometheusMiddleware_RoutesObserved(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	r := chi.NewRouter()
+	r.Use(PrometheusMiddleware(metricsRegistry))
+	for _, route := range []string{"/one", "/two", "/three/{id}"} {
+		r.Get(route, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	
+	// Repeated hits on the same route pattern are only counted once
+	for _, path := range []string{"/one", "/two", "/three/1", "/three/2", "/one"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	
+	metricsW := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(metricsW, httptest.NewRequest("GET", "/metrics", nil))
+	
+	if !strings.Contains(metricsW.Body.String(), "http_routes_observed 3") {
+		t.Error("Expected http_routes_observed to be 3")
+	}
+}
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Prometheus/2.47.0", "prometheus"},
+		{"curl/8.4.0", "curl"},
+		{"Mozilla/5.0 (X11; Linux x86_64)", "browser"},
+		{"Go-http-client/1.1", "other"},
+		{"", "other"},
+	}
+	
+	for _, tt := range tests {
+		if got := classifyUserAgent(tt.userAgent); got != tt.want {
+			t.Errorf("classifyUserAgent(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
 func TestGetRoutePattern(t *testing.T) {
 	// Test with chi router context
 	r := chi.NewRouter()
@@ -80,11 +195,11 @@ func TestGetRoutePattern(t *testing.T) {
 	
 	r.ServeHTTP(w, req)
 	
-	// Test without chi router context (fallback to path)
+	// Test without chi router context (fallback to a fixed label)
 	plainReq := httptest.NewRequest("GET", "/plain/path", nil)
 	pattern := getRoutePattern(plainReq)
-	if pattern != "/plain/path" {
-		t.Errorf("Expected route pattern '/plain/path', got '%s'", pattern)
+	if pattern != "unmatched" {
+		t.Errorf("Expected route pattern 'unmatched', got '%s'", pattern)
 	}
 }
 
@@ -98,7 +213,7 @@ func TestBearerTokenAuthMiddleware_ValidToken(t *testing.T) {
 	})
 
 	// Wrap with bearer token auth middleware
-	middleware := BearerTokenAuthMiddleware(adminToken)
+	middleware := BearerTokenAuthMiddleware(NewTokenStore(adminToken))
 	wrappedHandler := middleware(handler)
 
 	// Create test request with valid token
@@ -128,7 +243,7 @@ func TestBearerTokenAuthMiddleware_InvalidToken(t *testing.T) {
 	})
 
 	// Wrap with bearer token auth middleware
-	middleware := BearerTokenAuthMiddleware(adminToken)
+	middleware := BearerTokenAuthMiddleware(NewTokenStore(adminToken))
 	wrappedHandler := middleware(handler)
 
 	// Create test request with invalid token
@@ -155,7 +270,7 @@ func TestBearerTokenAuthMiddleware_MissingHeader(t *testing.T) {
 	})
 
 	// Wrap with bearer token auth middleware
-	middleware := BearerTokenAuthMiddleware(adminToken)
+	middleware := BearerTokenAuthMiddleware(NewTokenStore(adminToken))
 	wrappedHandler := middleware(handler)
 
 	// Create test request without Authorization header
@@ -181,7 +296,7 @@ func TestBearerTokenAuthMiddleware_InvalidFormat(t *testing.T) {
 	})
 
 	// Wrap with bearer token auth middleware
-	middleware := BearerTokenAuthMiddleware(adminToken)
+	middleware := BearerTokenAuthMiddleware(NewTokenStore(adminToken))
 	wrappedHandler := middleware(handler)
 
 	// Create test request with invalid format (missing "Bearer ")
@@ -198,13 +313,95 @@ func TestBearerTokenAuthMiddleware_InvalidFormat(t *testing.T) {
 	}
 }
 
+func TestBearerTokenAuthMiddleware_RotatedToken(t *testing.T) {
+	tokens := NewTokenStore("old-token")
+	
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	wrappedHandler := BearerTokenAuthMiddleware(tokens)(handler)
+	
+	tokens.Set("new-token")
+	
+	// The middleware reads the rotated token without being rebuilt
+	req := httptest.NewRequest("POST", "/admin", nil)
+	req.Header.Set("Authorization", "Bearer old-token")
+	w := httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for old token, got %d", w.Code)
+	}
+	
+	req = httptest.NewRequest("POST", "/admin", nil)
+	req.Header.Set("Authorization", "Bearer new-token")
+	w = httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for new token, got %d", w.Code)
+	}
+}
+
+func TestTokenStore_Valid(t *testing.T) {
+	tokens := NewTokenStore("test-admin-token")
+	
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{token: "test-admin-token", want: true},
+		{token: "test-admin-tokem", want: false},
+		{token: "test-admin", want: false},
+		{token: "test-admin-token-longer", want: false},
+		{token: "", want: false},
+	}
+	
+	for _, tt := range tests {
+		if got := tokens.Valid(tt.token); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestBearerTokenAuthMiddleware_MultipleTokens(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	wrappedHandler := BearerTokenAuthMiddleware(NewTokenStore("current-token", "next-token"))(handler)
+	
+	tests := []struct {
+		token      string
+		wantStatus int
+	}{
+		{token: "current-token", wantStatus: http.StatusOK},
+		{token: "next-token", wantStatus: http.StatusOK},
+		{token: "unknown-token", wantStatus: http.StatusUnauthorized},
+		{token: "current-token-suffix", wantStatus: http.StatusUnauthorized},
+		{token: "", wantStatus: http.StatusUnauthorized},
+	}
+	
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+		
+		if w.Code != tt.wantStatus {
+			t.Errorf("Token %q: expected status %d, got %d", tt.token, tt.wantStatus, w.Code)
+		}
+	}
+}
+
 // Mock error toggle for testing
 type mockErrorToggle struct {
 	shouldInject bool
 	statusCode   int
+	routes       []string
 }
 
-func (m *mockErrorToggle) ShouldInjectError() (bool, int) {
+func (m *mockErrorToggle) ShouldInjectError(route string) (bool, int) {
+	m.routes = append(m.routes, route)
 	return m.shouldInject, m.statusCode
 }
 
@@ -219,7 +416,7 @@ func TestErrorInjectionMiddleware_NoInjection(t *testing.T) {
 	})
 
 	// Wrap with error injection middleware
-	middleware := ErrorInjectionMiddleware(toggle)
+	middleware := ErrorInjectionMiddleware(toggle, metrics.NewRegistry())
 	wrappedHandler := middleware(handler)
 
 	// Create test request
@@ -249,7 +446,7 @@ func TestErrorInjectionMiddleware_WithInjection(t *testing.T) {
 	})
 
 	// Wrap with error injection middleware
-	middleware := ErrorInjectionMiddleware(toggle)
+	middleware := ErrorInjectionMiddleware(toggle, metrics.NewRegistry())
 	wrappedHandler := middleware(handler)
 
 	// Create test request
@@ -268,6 +465,75 @@ func TestErrorInjectionMiddleware_WithInjection(t *testing.T) {
 	}
 }
 
+func TestErrorInjectionMiddleware_InjectedErrorsMetric(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	toggle := &mockErrorToggle{shouldInject: true, statusCode: 503}
+	
+	r := chi.NewRouter()
+	r.With(ErrorInjectionMiddleware(toggle, metricsRegistry)).Get("/api/v1/work", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	
+	for i := 0; i < 3; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/work", nil))
+	}
+	
+	w := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	
+	if !strings.Contains(w.Body.String(), `injected_errors_total{route="/api/v1/work",status_code="503"} 3`) {
+		t.Error("Expected injected_errors_total to count 3 injected 503s on /api/v1/work")
+	}
+}
+
+func TestErrorInjectionMiddleware_ObservedRate(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	toggle := toggles.NewErrorToggle()
+	toggle.SetConfig(true, 0.5, 503)
+	
+	handler := ErrorInjectionMiddleware(toggle, metricsRegistry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	for i := 0; i < 2*errorInjectionWindowSize; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	}
+	
+	families, err := metricsRegistry.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	
+	observed := -1.0
+	for _, family := range families {
+		if family.GetName() == "error_injection_observed_rate" {
+			observed = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	
+	if observed < 0.4 || observed > 0.6 {
+		t.Errorf("Expected observed rate near 0.5, got %v", observed)
+	}
+}
+
+func TestInjectionWindow(t *testing.T) {
+	window := newInjectionWindow(4)
+	
+	if rate := window.record(true); rate != 1 {
+		t.Errorf("Expected rate 1 after one injection, got %v", rate)
+	}
+	window.record(false)
+	window.record(false)
+	if rate := window.record(false); rate != 0.25 {
+		t.Errorf("Expected rate 0.25 with a full window, got %v", rate)
+	}
+	
+	// The injected outcome is evicted once the window wraps
+	if rate := window.record(false); rate != 0 {
+		t.Errorf("Expected rate 0 after eviction, got %v", rate)
+	}
+}
+
 func TestErrorInjectionMiddleware_InvalidToggle(t *testing.T) {
 	// Create invalid toggle (doesn't implement the interface)
 	toggle := "invalid"
@@ -279,7 +545,7 @@ func TestErrorInjectionMiddleware_InvalidToggle(t *testing.T) {
 	})
 
 	// Wrap with error injection middleware
-	middleware := ErrorInjectionMiddleware(toggle)
+	middleware := ErrorInjectionMiddleware(toggle, metrics.NewRegistry())
 	wrappedHandler := middleware(handler)
 
 	// Create test request
@@ -296,4 +562,815 @@ func TestErrorInjectionMiddleware_InvalidToggle(t *testing.T) {
 	if w.Body.String() != "success" {
 		t.Errorf("Expected 'success', got %s", w.Body.String())
 	}
-}
\ No newline at end of file
+}
+
+func TestTimeoutMiddleware_HandlerAnswers(t *testing.T) {
+	// Handlers that notice the deadline keep their own response
+	handler := TimeoutMiddleware(20*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		writeJSONError(w, r, http.StatusRequestTimeout, "cancelled")
+	}))
+	
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/work", nil))
+	
+	if w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected the handler's status %d, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "timed out") {
+		t.Errorf("Expected no second response from the middleware, got %s", w.Body.String())
+	}
+	
+	// A zero timeout disables the limit
+	handler = TimeoutMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("Expected no deadline with a zero timeout")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/ping", nil))
+}
+
+func TestBodySamplingMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"accepted by the handler"}`))
+	})
+	
+	tests := []struct {
+		name       string
+		rate       float64
+		expectLogs int
+	}{
+		{name: "always sampled", rate: 1.0, expectLogs: 1},
+		{name: "never sampled", rate: 0.0, expectLogs: 0},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			middleware := BodySamplingMiddleware(zap.New(core), tt.rate, 20)
+			
+			body := `{"token": "super-secret", "name": "a fairly long request body"}`
+			req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			
+			middleware(handler).ServeHTTP(w, req)
+			
+			if w.Body.String() != `{"status":"accepted by the handler"}` {
+				t.Errorf("Expected response body to pass through, got %q", w.Body.String())
+			}
+			
+			samples := logs.FilterMessage("Body sample").All()
+			if len(samples) != tt.expectLogs {
+				t.Fatalf("Expected %d body samples, got %d", tt.expectLogs, len(samples))
+			}
+			if tt.expectLogs == 0 {
+				return
+			}
+			
+			fields := samples[0].ContextMap()
+			requestBody, _ := fields["request_body"].(string)
+			responseBody, _ := fields["response_body"].(string)
+			
+			if strings.Contains(requestBody, "super-secret") {
+				t.Errorf("Expected token to be redacted, got %q", requestBody)
+			}
+			if !strings.HasSuffix(requestBody, "...(truncated)") || len(requestBody) != 20+len("...(truncated)") {
+				t.Errorf("Expected request body truncated to 20 bytes, got %q", requestBody)
+			}
+			if responseBody != `{"status":"accepted ...(truncated)` {
+				t.Errorf("Expected truncated response body, got %q", responseBody)
+			}
+		})
+	}
+}
+
+func TestBodySamplingMiddleware_RequestBodyStillReadable(t *testing.T) {
+	var received string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	})
+	
+	middleware := BodySamplingMiddleware(zap.NewNop(), 1.0, 4)
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(`{"rate": 0.5}`))
+	middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+	
+	if received != `{"rate": 0.5}` {
+		t.Errorf("Expected handler to receive full body, got %q", received)
+	}
+}
+
+func TestErrorInjectionMiddleware_PassesRoutePattern(t *testing.T) {
+	toggle := &mockErrorToggle{}
+	
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(ErrorInjectionMiddleware(toggle, metrics.NewRegistry()))
+			r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+		})
+	})
+	
+	req := httptest.NewRequest("GET", "/api/v1/items/42", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	
+	if len(toggle.routes) != 1 || toggle.routes[0] != "/api/v1/items/{id}" {
+		t.Errorf("Expected route pattern /api/v1/items/{id}, got %v", toggle.routes)
+	}
+}
+
+// mockDelayToggle is a mock latency toggle returning a fixed delay
+type mockDelayToggle struct {
+	delay time.Duration
+}
+
+func (m *mockDelayToggle) ShouldDelay() time.Duration {
+	return m.delay
+}
+
+func TestLatencyInjectionMiddleware_Delay(t *testing.T) {
+	toggle := &mockDelayToggle{delay: 50 * time.Millisecond}
+	
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	wrappedHandler := LatencyInjectionMiddleware(toggle)(handler)
+	
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	wrappedHandler.ServeHTTP(w, req)
+	elapsed := time.Since(start)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("Expected request to be delayed by at least 50ms, took %v", elapsed)
+	}
+}
+
+func TestPrometheusMiddleware_HandlerDurationExcludesInjectedLatency(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	r := chi.NewRouter()
+	r.Use(PrometheusMiddleware(metricsRegistry))
+	r.Use(LatencyInjectionMiddleware(&mockDelayToggle{delay: 200 * time.Millisecond}))
+	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	
+	total := findHistogram(t, metricsRegistry, "http_request_duration_seconds")
+	handler := findHistogram(t, metricsRegistry, "http_handler_duration_seconds")
+	if total.GetSampleCount() != 1 || handler.GetSampleCount() != 1 {
+		t.Fatalf("Expected one observation each, got %d and %d", total.GetSampleCount(), handler.GetSampleCount())
+	}
+	if total.GetSampleSum() < 0.2 {
+		t.Errorf("Expected the total duration to include the 200ms delay, got %vs", total.GetSampleSum())
+	}
+	if handler.GetSampleSum() > 0.05 {
+		t.Errorf("Expected the handler duration to exclude the delay, got %vs", handler.GetSampleSum())
+	}
+}
+
+func TestLatencyInjectionMiddleware_Cancellation(t *testing.T) {
+	toggle := &mockDelayToggle{delay: 5 * time.Second}
+	
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	wrappedHandler := LatencyInjectionMiddleware(toggle)(handler)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	
+	req := httptest.NewRequest("GET", "/test", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	wrappedHandler.ServeHTTP(w, req)
+	
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected delay to stop on cancellation, took %v", elapsed)
+	}
+	if called {
+		t.Error("Expected handler not to run after cancellation")
+	}
+}
+
+func TestLatencyInjectionMiddleware_InvalidToggle(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	
+	// An unsupported toggle results in a no-op middleware
+	wrappedHandler := LatencyInjectionMiddleware("invalid")(handler)
+	
+	w := httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestHeaderErrorInjectionMiddleware(t *testing.T) {
+	handler := HeaderErrorInjectionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}))
+	
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+	}{
+		{name: "no header", header: "", expectedStatus: http.StatusOK},
+		{name: "server error", header: "503", expectedStatus: http.StatusServiceUnavailable},
+		{name: "client error", header: "429", expectedStatus: http.StatusTooManyRequests},
+		{name: "success status rejected", header: "200", expectedStatus: http.StatusBadRequest},
+		{name: "non-numeric rejected", header: "boom", expectedStatus: http.StatusBadRequest},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+			if tt.header != "" {
+				req.Header.Set(InjectErrorHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			
+			handler.ServeHTTP(w, req)
+			
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestStrictQueryParamsMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("success"))
+	})
+	
+	wrappedHandler := StrictQueryParamsMiddleware([]string{"ms", "jitter"})(handler)
+	
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"no params", "", http.StatusOK, "success"},
+		{"known params", "?ms=100&jitter=10", http.StatusOK, "success"},
+		{"unknown param", "?ms2=100", http.StatusBadRequest, "Unknown query parameters: ms2"},
+		{"mixed params", "?ms=100&zeta=1&alpha=2", http.StatusBadRequest, "Unknown query parameters: alpha, zeta"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/work"+tt.query, nil)
+			w := httptest.NewRecorder()
+			
+			wrappedHandler.ServeHTTP(w, req)
+			
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_Preflight(t *testing.T) {
+	called := false
+	handler := CORSMiddleware([]string{"https://tools.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	
+	req := httptest.NewRequest("OPTIONS", "/api/v1/ping", nil)
+	req.Header.Set("Origin", "https://tools.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if called {
+		t.Error("Expected preflight not to reach the handler")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://tools.example.com" {
+		t.Errorf("Expected the origin to be allowed, got %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") == "" || w.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Error("Expected allowed methods and headers on the preflight response")
+	}
+}
+
+func TestCORSMiddleware_ActualRequest(t *testing.T) {
+	handler := CORSMiddleware([]string{"https://tools.example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	tests := []struct {
+		name        string
+		origin      string
+		allowOrigin string
+	}{
+		{name: "allowed origin", origin: "https://tools.example.com", allowOrigin: "https://tools.example.com"},
+		{name: "other origin", origin: "https://evil.example.com", allowOrigin: ""},
+		{name: "no origin", origin: "", allowOrigin: ""},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			
+			handler.ServeHTTP(w, req)
+			
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.allowOrigin, got)
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_Wildcard(t *testing.T) {
+	handler := CORSMiddleware([]string{"*"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	
+	req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected Access-Control-Allow-Origin *, got %q", got)
+	}
+}
+
+func TestMaxURLLengthMiddleware(t *testing.T) {
+	handler := MaxURLLengthMiddleware(32)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "under limit", url: "/api/v1/work?ms=10", wantStatus: http.StatusOK},
+		{name: "at limit", url: "/api/v1/work?ms=10&jitter=123456", wantStatus: http.StatusOK},
+		{name: "over limit", url: "/api/v1/work?ms=10&jitter=" + strings.Repeat("1", 32), wantStatus: http.StatusRequestURITooLong},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+			
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytesMiddleware(t *testing.T) {
+	var received string
+	handler := MaxBodyBytesMiddleware(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	// Bodies within the limit reach the handler intact
+	req := httptest.NewRequest("POST", "/api/v1/toggles/latency", strings.NewReader(`{"enabled":true}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if received != `{"enabled":true}` {
+		t.Errorf("Expected the full body to be passed on, got %q", received)
+	}
+	
+	// Oversized bodies are rejected, with or without a Content-Length
+	for _, contentLength := range []int64{17, -1} {
+		req = httptest.NewRequest("POST", "/api/v1/toggles/latency", strings.NewReader(`{"enabled": true}`))
+		req.ContentLength = contentLength
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Content-Length %d: expected status %d, got %d", contentLength, http.StatusRequestEntityTooLarge, w.Code)
+		}
+	}
+}
+
+func TestMiddleware_ErrorsIncludeRequestID(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	
+	// Spend the only token so the next request is limited
+	rateLimit := RateLimitMiddleware(metrics.NewRegistry(), 1, 1, false)
+	rateLimit(ok).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/toggles/latency", nil))
+	
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		header     string
+		value      string
+		body       string
+		query      string
+		status     int
+	}{
+		{"missing token", BearerTokenAuthMiddleware(NewTokenStore("test-token")), "", "", "", "", http.StatusUnauthorized},
+		{"invalid token", BearerTokenAuthMiddleware(NewTokenStore("test-token")), "Authorization", "Bearer wrong", "", "", http.StatusUnauthorized},
+		{"body too large", MaxBodyBytesMiddleware(4), "", "", "too large", "", http.StatusRequestEntityTooLarge},
+		{"injected error", ErrorInjectionMiddleware(&mockErrorToggle{shouldInject: true, statusCode: 503}, metrics.NewRegistry()), "", "", "", "", http.StatusServiceUnavailable},
+		{"header injected error", HeaderErrorInjectionMiddleware, InjectErrorHeader, "502", "", "", http.StatusBadGateway},
+		{"invalid injected status", HeaderErrorInjectionMiddleware, InjectErrorHeader, "200", "", "", http.StatusBadRequest},
+		{"rate limited", rateLimit, "", "", "", "", http.StatusTooManyRequests},
+		{"unknown query parameter", StrictQueryParamsMiddleware(nil), "", "", "", "verbose=1", http.StatusBadRequest},
+		{"url too long", MaxURLLengthMiddleware(8), "", "", "", "", http.StatusRequestURITooLong},
+		{"too many streams", StreamLimitMiddleware(metrics.NewRegistry(), 0), "", "", "", "", http.StatusServiceUnavailable},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.RequestID(RequestIDMiddleware(tt.middleware(ok)))
+			
+			req := httptest.NewRequest("POST", "/api/v1/toggles/latency?"+tt.query, strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected a JSON response, got %q", w.Header().Get("Content-Type"))
+			}
+			
+			var response errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Status != tt.status {
+				t.Errorf("Expected status %d in the body, got %d", tt.status, response.Status)
+			}
+			if headerID := w.Header().Get("X-Request-ID"); headerID == "" || response.RequestID != headerID {
+				t.Errorf("Expected request_id %q to match X-Request-ID, got %q", headerID, response.RequestID)
+			}
+		})
+	}
+}
+
+func TestStreamLimitMiddleware(t *testing.T) {
+	const limit = 2
+	
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := StreamLimitMiddleware(metrics.NewRegistry(), limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	// Hold connections open up to the cap
+	results := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+			results <- w.Code
+		}()
+		<-started
+	}
+	
+	// The next connection is over the cap
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 over the cap, got %d", w.Code)
+	}
+	
+	close(release)
+	for i := 0; i < limit; i++ {
+		if code := <-results; code != http.StatusOK {
+			t.Errorf("Expected connections within the cap to succeed, got %d", code)
+		}
+	}
+	
+	// Closed connections free their slots
+	go func() { <-started }()
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 once connections closed, got %d", w.Code)
+	}
+}
+
+func TestPanicRecoveryMiddleware(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	handler := PanicRecoveryMiddleware(zap.NewNop(), metricsRegistry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("panic") == "true" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/test?panic=true", nil))
+	
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d after a panic, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if panics := findCounter(t, metricsRegistry, "panics_recovered_total"); panics != 1 {
+		t.Errorf("Expected panics_recovered_total 1, got %v", panics)
+	}
+	
+	// Requests that don't panic are not counted
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if panics := findCounter(t, metricsRegistry, "panics_recovered_total"); panics != 1 {
+		t.Errorf("Expected panics_recovered_total to stay 1, got %v", panics)
+	}
+}
+
+func TestStreamOnly(t *testing.T) {
+	applied := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Applied", "true")
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := StreamOnly(applied)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	tests := []struct {
+		query   string
+		applied bool
+	}{
+		{"", false},
+		{"?stream=false", false},
+		{"?stream=true", true},
+		{"?stream=1", true},
+	}
+	
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/work"+tt.query, nil))
+		
+		if got := w.Header().Get("X-Applied") == "true"; got != tt.applied {
+			t.Errorf("%q: expected middleware applied=%v, got %v", tt.query, tt.applied, got)
+		}
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		accessLog AccessLogConfig
+		messages  []string
+		optional  map[string]interface{}
+	}{
+		{
+			name:      "start and completion lines",
+			accessLog: AccessLogConfig{LogRequestStart: true},
+			messages:  []string{"Request started", "Request completed"},
+			optional:  map[string]interface{}{},
+		},
+		{
+			name:      "single completion line with optional fields",
+			accessLog: AccessLogConfig{Fields: []string{"query", "bytes", "user_agent", "client_ip"}, TrustProxy: true},
+			messages:  []string{"Request completed"},
+			optional: map[string]interface{}{
+				"query":      "ms=10",
+				"bytes":      int64(5),
+				"user_agent": "test-agent",
+				"client_ip":  "198.51.100.1",
+			},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			handler := middleware.RequestID(RequestIDMiddleware(LoggingMiddleware(zap.New(core), tt.accessLog)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("hello"))
+			}))))
+			
+			req := httptest.NewRequest("GET", "/api/v1/work?ms=10", nil)
+			req.Header.Set("User-Agent", "test-agent")
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			
+			entries := logs.All()
+			if len(entries) != len(tt.messages) {
+				t.Fatalf("Expected %d log lines, got %d", len(tt.messages), len(entries))
+			}
+			for i, message := range tt.messages {
+				if entries[i].Message != message {
+					t.Errorf("Expected log line %d to be %q, got %q", i, message, entries[i].Message)
+				}
+			}
+			
+			fields := entries[len(entries)-1].ContextMap()
+			if fields["method"] != "GET" || fields["path"] != "/api/v1/work" || fields["status"] != int64(http.StatusCreated) {
+				t.Errorf("Expected method, path and status on the completion line, got %v", fields)
+			}
+			if _, ok := fields["duration"]; !ok {
+				t.Error("Expected duration on the completion line")
+			}
+			if fields["request_id"] != w.Header().Get("X-Request-ID") {
+				t.Errorf("Expected request_id %q, got %v", w.Header().Get("X-Request-ID"), fields["request_id"])
+			}
+			for _, key := range []string{"query", "bytes", "remote_addr", "client_ip", "user_agent"} {
+				want, enabled := tt.optional[key]
+				got, present := fields[key]
+				if present != enabled {
+					t.Errorf("Expected field %s present=%v, got %v", key, enabled, present)
+				} else if enabled && got != want {
+					t.Errorf("Expected %s %v, got %v", key, want, got)
+				}
+			}
+		})
+	}
+}
+
+// newTestTracer returns a tracer whose spans are exported synchronously to
+// the returned in-memory exporter
+func newTestTracer() (trace.Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return provider.Tracer("test"), exporter
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	
+	core, logs := observer.New(zap.InfoLevel)
+	
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(TracingMiddleware(tracer))
+	r.Use(LoggingMiddleware(zap.New(core), AccessLogConfig{LogRequestStart: true}))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+	
+	req := httptest.NewRequest("GET", "/items/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	
+	if !handlerSpan.IsValid() {
+		t.Fatal("Expected the span in the request context")
+	}
+	if handlerSpan.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the incoming trace to be continued, got %s", handlerSpan.TraceID())
+	}
+	expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + handlerSpan.SpanID().String() + "-01"
+	if w.Header().Get("traceparent") != expected {
+		t.Errorf("Expected the response traceparent %q to name the server span, got %q", expected, w.Header().Get("traceparent"))
+	}
+	
+	for _, entry := range logs.All() {
+		if entry.ContextMap()["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected %q to carry the trace ID, got %v", entry.Message, entry.ContextMap())
+		}
+	}
+	
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one exported span, got %d", len(spans))
+	}
+	if spans[0].Name != "GET /items/{id}" {
+		t.Errorf("Expected the span to be named after the route, got %q", spans[0].Name)
+	}
+	if spans[0].Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the caller's span as parent, got %s", spans[0].Parent.SpanID())
+	}
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[0].Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	if attributes["http.method"].AsString() != "GET" || attributes["http.route"].AsString() != "/items/{id}" || attributes["http.status_code"].AsInt64() != http.StatusTeapot {
+		t.Errorf("Unexpected span attributes %v", spans[0].Attributes)
+	}
+}
+
+func TestTracingMiddleware_NewTrace(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	
+	handler := TracingMiddleware(tracer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "not-a-traceparent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || !spans[0].SpanContext.IsValid() || spans[0].Parent.IsValid() {
+		t.Error("Expected an invalid traceparent to start a new trace")
+	}
+}
+
+func TestTracingMiddleware_ServerErrorStatus(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	
+	handler := TracingMiddleware(tracer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error {
+		t.Errorf("Expected a 502 to mark the span as failed, got %+v", spans)
+	}
+}
+
+func TestTracingMiddleware_IDHeaders(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	
+	handler := TracingMiddleware(tracer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one exported span, got %d", len(spans))
+	}
+	
+	if got := w.Header().Get("X-Trace-ID"); got == "" || got != spans[0].SpanContext.TraceID().String() {
+		t.Errorf("Expected X-Trace-ID %s, got %q", spans[0].SpanContext.TraceID(), got)
+	}
+	if got := w.Header().Get("X-Span-ID"); got == "" || got != spans[0].SpanContext.SpanID().String() {
+		t.Errorf("Expected X-Span-ID %s, got %q", spans[0].SpanContext.SpanID(), got)
+	}
+}
+
+func TestJSONEncodeErrorMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	metricsRegistry := metrics.NewRegistry()
+	
+	healthHandlers := NewHealthHandlers(health.NewChecker())
+	r := chi.NewRouter()
+	r.Use(JSONEncodeErrorMiddleware(zap.New(core), metricsRegistry))
+	r.NotFound(NotFoundHandler)
+	r.Get("/readyz", healthHandlers.Readiness)
+	
+	// Error responses and handlers outside this package report failures too
+	r.ServeHTTP(&failingResponseWriter{}, httptest.NewRequest("GET", "/missing", nil))
+	
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	req.Header.Set("Accept", "application/json")
+	r.ServeHTTP(&failingResponseWriter{}, req)
+	
+	w := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	
+	for _, endpoint := range []string{unmatchedRoute, "/readyz"} {
+		if !strings.Contains(w.Body.String(), `http_json_encode_errors_total{endpoint="`+endpoint+`"} 1`) {
+			t.Errorf("Expected http_json_encode_errors_total to be incremented for %s", endpoint)
+		}
+	}
+	if n := logs.FilterMessage("Failed to encode JSON response").Len(); n != 2 {
+		t.Errorf("Expected 2 encode failures to be logged, got %d", n)
+	}
+}
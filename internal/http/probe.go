@@ -0,0 +1,124 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"monitoring-dashboard-automation/internal/httpjson"
+
+	"go.uber.org/zap"
+)
+
+// probeTimeout bounds a single outbound probe
+const probeTimeout = 5 * time.Second
+
+// errProbeTargetNotAllowed is returned when a probe or one of its redirects
+// leaves the allowlist
+var errProbeTargetNotAllowed = errors.New("probe target not allowed")
+
+// probeAllowlist holds the hosts and networks the probe endpoint may reach.
+// Entries are host names, IP addresses or CIDR ranges.
+type probeAllowlist struct {
+	hosts map[string]bool
+	nets  []*net.IPNet
+}
+
+func newProbeAllowlist(entries []string) *probeAllowlist {
+	allowlist := &probeAllowlist{hosts: make(map[string]bool)}
+	for _, entry := range entries {
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			allowlist.nets = append(allowlist.nets, ipNet)
+			continue
+		}
+		allowlist.hosts[strings.ToLower(entry)] = true
+	}
+	return allowlist
+}
+
+// allows reports whether host may be probed. Host names must be listed
+// exactly; IP addresses must be listed or fall inside a listed range, so
+// private and loopback addresses are rejected unless explicitly allowed.
+func (a *probeAllowlist) allows(host string) bool {
+	host = strings.ToLower(host)
+	if a.hosts[host] {
+		return true
+	}
+	
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range a.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeHandler handles GET /api/v1/probe?url=... - fetches the target and
+// reports its status and latency. Targets outside allowedHosts, including
+// redirects to them, are rejected with 403 to prevent SSRF into internal networks.
+func ProbeHandler(logger *zap.Logger, allowedHosts []string) http.HandlerFunc {
+	allowlist := newProbeAllowlist(allowedHosts)
+	client := &http.Client{
+		Timeout: probeTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !allowlist.allows(req.URL.Hostname()) {
+				return errProbeTargetNotAllowed
+			}
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+	
+	return func(w http.ResponseWriter, r *http.Request) {
+		target, err := url.Parse(r.URL.Query().Get("url"))
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			writeJSONError(w, r, http.StatusBadRequest, "url must be an absolute http or https URL")
+			return
+		}
+		
+		if !allowlist.allows(target.Hostname()) {
+			logger.Warn("Probe target rejected", zap.String("host", target.Hostname()))
+			writeJSONError(w, r, http.StatusForbidden, "probe target not allowed")
+			return
+		}
+		
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target.String(), nil)
+		if err != nil {
+			writeJSONError(w, r, http.StatusBadRequest, "invalid probe target")
+			return
+		}
+		
+		start := time.Now()
+		resp, err := client.Do(req)
+		duration := time.Since(start)
+		if err != nil {
+			if errors.Is(err, errProbeTargetNotAllowed) {
+				writeJSONError(w, r, http.StatusForbidden, "probe redirect target not allowed")
+				return
+			}
+			logger.Warn("Probe failed", zap.String("host", target.Hostname()), zap.Error(err))
+			writeJSONError(w, r, http.StatusBadGateway, "probe failed")
+			return
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		
+		response := map[string]interface{}{
+			"target":      target.String(),
+			"status_code": resp.StatusCode,
+			"duration_ms": duration.Milliseconds(),
+		}
+		
+		httpjson.Write(w, r, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestProbeAllowlist(t *testing.T) {
+	allowlist := newProbeAllowlist([]string{"status.example.com", "203.0.113.0/24"})
+	
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "status.example.com", want: true},
+		{host: "STATUS.example.com", want: true},
+		{host: "203.0.113.7", want: true},
+		{host: "other.example.com", want: false},
+		{host: "127.0.0.1", want: false},
+		{host: "10.0.0.1", want: false},
+		{host: "169.254.169.254", want: false},
+		{host: "::1", want: false},
+	}
+	
+	for _, tt := range tests {
+		if got := allowlist.allows(tt.host); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestProbeHandler_AllowedTarget(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer target.Close()
+	
+	handler := ProbeHandler(zap.NewNop(), []string{"127.0.0.0/8"})
+	
+	req := httptest.NewRequest("GET", "/api/v1/probe?url="+url.QueryEscape(target.URL), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	
+	var response struct {
+		StatusCode int `json:"status_code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.StatusCode != http.StatusTeapot {
+		t.Errorf("Expected probed status %d, got %d", http.StatusTeapot, response.StatusCode)
+	}
+}
+
+func TestProbeHandler_RejectedTargets(t *testing.T) {
+	probed := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed = true
+	}))
+	defer internal.Close()
+	
+	// An allowed host (reached as localhost) that redirects to the
+	// internal server's unlisted 127.0.0.1 address
+	redirector := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer redirector.Close()
+	redirectorURL, _ := url.Parse(redirector.URL)
+	redirectorURL.Host = "localhost:" + redirectorURL.Port()
+	
+	handler := ProbeHandler(zap.NewNop(), []string{"status.example.com", "localhost"})
+	
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{name: "private address", target: internal.URL, wantStatus: http.StatusForbidden},
+		{name: "metadata address", target: "http://169.254.169.254/latest/meta-data", wantStatus: http.StatusForbidden},
+		{name: "unlisted host", target: "http://internal.example.com/", wantStatus: http.StatusForbidden},
+		{name: "redirect to private address", target: redirectorURL.String(), wantStatus: http.StatusForbidden},
+		{name: "unsupported scheme", target: "file:///etc/passwd", wantStatus: http.StatusBadRequest},
+		{name: "missing url", target: "", wantStatus: http.StatusBadRequest},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/probe?url="+url.QueryEscape(tt.target), nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+	
+	if probed {
+		t.Error("Expected the internal server never to be reached")
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"monitoring-dashboard-automation/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRateLimitMiddleware_Burst(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	
+	r := chi.NewRouter()
+	r.With(RateLimitMiddleware(metricsRegistry, 1, 3, false)).Get("/api/v1/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	
+	codes := make([]int, 5)
+	var retryAfter string
+	for i := range codes {
+		req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		
+		r.ServeHTTP(w, req)
+		
+		codes[i] = w.Code
+		if w.Code == http.StatusTooManyRequests {
+			retryAfter = w.Header().Get("Retry-After")
+		}
+	}
+	
+	expected := []int{200, 200, 200, 429, 429}
+	for i := range expected {
+		if codes[i] != expected[i] {
+			t.Errorf("Expected status codes %v, got %v", expected, codes)
+			break
+		}
+	}
+	if retryAfter != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", retryAfter)
+	}
+	
+	w := httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	
+	if !strings.Contains(w.Body.String(), `rate_limited_requests_total{route="/api/v1/ping"} 2`) {
+		t.Error("Expected rate_limited_requests_total to count 2 limited requests")
+	}
+}
+
+func TestRateLimitMiddleware_SeparateClients(t *testing.T) {
+	handler := RateLimitMiddleware(metrics.NewRegistry(), 1, 1, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	
+	send := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	
+	if code := send("10.0.0.1:1234", ""); code != http.StatusOK {
+		t.Errorf("Expected first request from 10.0.0.1 to pass, got %d", code)
+	}
+	if code := send("10.0.0.1:5678", ""); code != http.StatusTooManyRequests {
+		t.Errorf("Expected second request from 10.0.0.1 to be limited, got %d", code)
+	}
+	if code := send("10.0.0.2:1234", ""); code != http.StatusOK {
+		t.Errorf("Expected 10.0.0.2 to have its own bucket, got %d", code)
+	}
+	
+	// Clients behind a trusted proxy are told apart by X-Forwarded-For
+	if code := send("10.0.0.9:1234", "192.0.2.1, 10.0.0.9"); code != http.StatusOK {
+		t.Errorf("Expected 192.0.2.1 to have its own bucket, got %d", code)
+	}
+	if code := send("10.0.0.9:1234", "192.0.2.2"); code != http.StatusOK {
+		t.Errorf("Expected 192.0.2.2 to have its own bucket, got %d", code)
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(2, 1)
+	limiter.now = func() time.Time { return now }
+	
+	if ok, _ := limiter.allow("client"); !ok {
+		t.Fatal("Expected the first request to pass")
+	}
+	ok, wait := limiter.allow("client")
+	if ok {
+		t.Fatal("Expected the bucket to be empty")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms for the next token, got %v", wait)
+	}
+	
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.allow("client"); !ok {
+		t.Error("Expected a token after refilling")
+	}
+}
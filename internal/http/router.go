@@ -1,65 +1,389 @@
 package http
 
 import (
+	"net/http"
+	"time"
+
+	"monitoring-dashboard-automation/internal/audit"
 	"monitoring-dashboard-automation/internal/config"
 	"monitoring-dashboard-automation/internal/health"
+	"monitoring-dashboard-automation/internal/logging"
 	"monitoring-dashboard-automation/internal/metrics"
 	"monitoring-dashboard-automation/internal/toggles"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// NewRouter creates and configures the HTTP router
-func NewRouter(cfg *config.Config, logger *zap.Logger, metricsRegistry *metrics.Registry) *chi.Mux {
+// Toggles are the fault injection toggles served under /api/v1/toggles
+type Toggles struct {
+	Error   *toggles.ErrorToggle
+	Latency *toggles.LatencyToggle
+	Memory  *toggles.MemoryToggle
+}
+
+// NewToggles creates the fault injection toggles, all disabled
+func NewToggles() *Toggles {
+	return &Toggles{
+		Error:   toggles.NewErrorToggle(),
+		Latency: toggles.NewLatencyToggle(),
+		Memory:  toggles.NewMemoryToggle(),
+	}
+}
+
+// NewRouter creates and configures the HTTP router. tracer may be nil when
+// tracing is disabled, and injection nil to use fresh toggles.
+func NewRouter(cfg *config.Config, logger *zap.Logger, logLevels *logging.Levels, metricsRegistry *metrics.Registry, healthChecker *health.Checker, tracer trace.Tracer, injection *Toggles) *chi.Mux {
 	r := chi.NewRouter()
 
-	// Create error toggle for error injection
-	errorToggle := toggles.NewErrorToggle()
+	// Everything logged from here on belongs to the http component
+	logger = logger.Named("http")
+
+	// Toggles for error and latency injection and simulated memory pressure
+	if injection == nil {
+		injection = NewToggles()
+	}
+	errorToggle := injection.Error
+	latencyToggle := injection.Latency
+	memoryToggle := injection.Memory
+
+	// Admin token store, rotatable at runtime
+	tokens := NewTokenStore(cfg.ValidAdminTokens()...)
+
+	// Create audit log for admin actions
+	auditLog := audit.NewLog(audit.DefaultCapacity)
+
+	// Middleware applied to /api/v1 routes, in order and with the scope each
+	// applies to, for GET /api/v1/debug/middleware
+	var chain []middlewareEntry
+	use := func(r middlewareUser, scope, name string, mw func(http.Handler) http.Handler) {
+		r.Use(mw)
+		chain = append(chain, middlewareEntry{Name: name, Scope: scope})
+	}
+
+	// Access log lines and fields, from LOG_REQUEST_START and LOG_ACCESS_FIELDS
+	accessLog := AccessLogConfig{
+		LogRequestStart: cfg.LogRequestStart,
+		Fields:          cfg.LogAccessFields,
+		TrustProxy:      cfg.TrustProxy,
+	}
+
+	// Route patterns registered without any timeout, for long-lived streams
+	timeoutExempt := make(map[string]bool, len(cfg.TimeoutExemptRoutes))
+	for _, pattern := range cfg.TimeoutExemptRoutes {
+		timeoutExempt[pattern] = true
+	}
 
 	// Apply middleware stack in order
-	r.Use(middleware.RequestID)           // Chi's built-in request ID middleware
-	r.Use(RequestIDMiddleware)            // Our custom request ID middleware
-	r.Use(PanicRecoveryMiddleware(logger)) // Panic recovery with logging
-	r.Use(LoggingMiddleware(logger))      // Structured logging
-	r.Use(PrometheusMiddleware(metricsRegistry)) // Prometheus instrumentation
-	r.Use(middleware.Timeout(60))         // Request timeout
-
-	// Create health checker and handlers
-	healthChecker := health.NewChecker()
+	use(r, scopeGlobal, "middleware.RequestID", middleware.RequestID)             // Chi's built-in request ID middleware
+	use(r, scopeGlobal, "RequestIDMiddleware", RequestIDMiddleware)                // Our custom request ID middleware
+	use(r, scopeGlobal, "JSONEncodeErrorMiddleware", JSONEncodeErrorMiddleware(logger, metricsRegistry)) // Log and count JSON bodies that fail to encode
+	if tracer != nil {
+		use(r, scopeGlobal, "TracingMiddleware", TracingMiddleware(tracer)) // Span per request, before logging so logs carry the trace ID
+	}
+	use(r, scopeGlobal, "LoggingMiddleware", LoggingMiddleware(logger, accessLog))  // Structured access logging
+	if cfg.MaxURLLength > 0 {
+		use(r, scopeGlobal, "MaxURLLengthMiddleware", MaxURLLengthMiddleware(cfg.MaxURLLength)) // Reject overlong URLs
+	}
+	if cfg.LogBodySampleRate > 0 {
+		use(r, scopeGlobal, "BodySamplingMiddleware", BodySamplingMiddleware(logger, cfg.LogBodySampleRate, cfg.LogBodyMaxBytes)) // Sampled body logging
+	}
+	use(r, scopeGlobal, "PrometheusMiddleware", PrometheusMiddleware(metricsRegistry)) // Prometheus instrumentation
+	use(r, scopeGlobal, "PanicRecoveryMiddleware", PanicRecoveryMiddleware(logger, metricsRegistry)) // Panic recovery, inside logging and metrics so the 500 is recorded
+
+	// Track how many readiness checks are registered
+	healthChecker.OnCheckCountChange(metricsRegistry.SetDynamicChecksCount)
+
+	// Record every readiness outcome for dashboards
+	healthChecker.OnReport(func(report *health.Report) {
+		metricsRegistry.SetReadinessUp(report.Status != health.StatusNotReady)
+		for _, check := range report.Checks {
+			if check.Status == "fail" {
+				metricsRegistry.IncReadinessCheckFailure(check.Name)
+			}
+		}
+	})
+
+	// Record how long each readiness outage lasted
+	healthChecker.OnRecovery(metricsRegistry.ObserveReadinessOutage)
+
+	// Create health handlers
 	healthHandlers := NewHealthHandlers(healthChecker)
 	
 	// Create API handlers
-	apiHandlers := NewAPIHandlers(logger, metricsRegistry)
+	apiHandlers := NewAPIHandlers(logger, metricsRegistry, WorkConfig{
+		DefaultMs:     cfg.DefaultWorkMs,
+		DefaultJitter: cfg.DefaultWorkJitter,
+	})
 	
 	// Create toggle handlers
-	toggleHandlers := NewToggleHandlers(logger, errorToggle)
+	toggleHandlers := NewToggleHandlers(logger, errorToggle, latencyToggle, memoryToggle)
 
-	// Health check routes (no error injection)
-	r.Get("/healthz", healthHandlers.Liveness)
-	r.Get("/readyz", healthHandlers.Readiness)
+	// Create admin handlers
+	adminHandlers := NewAdminHandlers(logger, tokens)
 
-	// Metrics endpoint (no error injection)
-	r.Handle("/metrics", metricsRegistry.GetHandler())
+	// Unknown paths and wrong methods get JSON errors like the rest of the
+	// API; set before the /api/v1 sub-router is mounted so it inherits the 404
+	r.NotFound(NotFoundHandler)
+	r.MethodNotAllowed(MethodNotAllowedHandler(r))
+
+	// Probe and metrics routes (no error injection), never cached by
+	// scrapers, load balancers or proxies in between
+	probes := newTimedRoutes(r, "", cfg.RequestTimeout, timeoutExempt)
+	probes.Use(NoStoreMiddleware)
+
+	// Health check routes, at configurable paths for platforms that probe elsewhere
+	probes.Get(pathOrDefault(cfg.LivenessPath, "/healthz"), healthHandlers.Liveness)
+	probes.Get(pathOrDefault(cfg.ReadinessPath, "/readyz"), healthHandlers.Readiness)
+	probes.Get("/startupz", healthHandlers.Startup)
+
+	// Metrics endpoint, optionally behind the admin token
+	metricsPath := pathOrDefault(cfg.MetricsPath, "/metrics")
+	metricsHandler := metricsRegistry.GetHandlerWithTimeout(cfg.MetricsScrapeTimeout)
+	if cfg.ProtectMetrics {
+		probes.With(BearerTokenAuthMiddleware(tokens)).Handle(metricsPath, metricsHandler)
+	} else {
+		probes.Handle(metricsPath, metricsHandler)
+	}
 
 	// API routes with error injection middleware
+	root := r
 	r.Route("/api/v1", func(r chi.Router) {
-		// Apply error injection middleware to API routes
-		r.Use(ErrorInjectionMiddleware(errorToggle))
-		
-		r.Get("/ping", apiHandlers.Ping)
-		r.Get("/work", apiHandlers.Work)
-
-		// Admin routes with bearer token authentication
-		r.Route("/toggles", func(r chi.Router) {
-			// Apply bearer token authentication to admin routes
-			r.Use(BearerTokenAuthMiddleware(cfg.AdminToken))
-			
-			r.Post("/error-rate", toggleHandlers.ErrorRate)
-			r.Post("/readiness", healthHandlers.ToggleReadiness)
+		// Wrong methods get a JSON 405 listing the allowed methods
+		r.MethodNotAllowed(MethodNotAllowedHandler(root))
+
+		// CORS runs before routing so preflight OPTIONS requests are answered
+		if len(cfg.CORSAllowedOrigins) > 0 {
+			use(r, scopeAPI, "CORSMiddleware", CORSMiddleware(cfg.CORSAllowedOrigins))
+		}
+
+		// Routes are registered in groups rather than sub-routers so the
+		// injection middleware runs after routing and sees the full route
+		// pattern. Work routes run under WORK_TIMEOUT, the rest under
+		// REQUEST_TIMEOUT, and TIMEOUT_EXEMPT_ROUTES under neither.
+		api := newTimedRoutes(r, "/api/v1", cfg.RequestTimeout, timeoutExempt)
+		work := newTimedRoutes(r, "/api/v1", cfg.WorkTimeout, timeoutExempt)
+		chain = append(chain, middlewareEntry{Name: "TimeoutMiddleware", Scope: scopeAPI})
+
+		// Middleware below is created once and shared by both groups, so rate
+		// limits and recorded responses are shared across them
+		apiGroups := routeGroups{api, work}
+
+		// Shed load once too many requests are in flight, then per client,
+		// before doing any work
+		if cfg.MaxConcurrentRequests > 0 {
+			use(apiGroups, scopeAPI, "ConcurrencyLimitMiddleware", ConcurrencyLimitMiddleware(metricsRegistry, cfg.MaxConcurrentRequests))
+		}
+		if cfg.RateLimitRPS > 0 {
+			use(apiGroups, scopeAPI, "RateLimitMiddleware", RateLimitMiddleware(metricsRegistry, cfg.RateLimitRPS, cfg.RateLimitBurst, cfg.TrustProxy))
+		}
+
+		// Every route except the admin ones, which stay on api without replay
+		// or fault injection; work routes are never admin routes
+		service := api.With()
+		serviceGroups := routeGroups{service, work}
+
+		// Replay responses for retried requests before injecting anything,
+		// so a retry sees the same result as the original attempt. Admin
+		// routes are left out so a recorded response never bypasses auth.
+		if cfg.IdempotencyTTL > 0 {
+			use(serviceGroups, scopeNonAdmin, "IdempotencyMiddleware", IdempotencyMiddleware(metricsRegistry, cfg.IdempotencyTTL, cfg.TrustProxy))
+		}
+
+		// Apply latency and error injection middleware to non-admin API
+		// routes; admin routes stay reachable so injection can be switched off
+		use(serviceGroups, scopeNonAdmin, "LatencyInjectionMiddleware", LatencyInjectionMiddleware(latencyToggle))
+		use(serviceGroups, scopeNonAdmin, "ErrorInjectionMiddleware", ErrorInjectionMiddleware(errorToggle, metricsRegistry))
+		use(serviceGroups, scopeNonAdmin, "HeaderErrorInjectionMiddleware", HeaderErrorInjectionMiddleware)
+
+		service.Get("/ping", apiHandlers.Ping)
+		service.Get("/echo", apiHandlers.Echo)
+		// Outbound HTTP probes, only to allowlisted targets
+		if len(cfg.ProbeAllowedHosts) > 0 {
+			service.Get("/probe", ProbeHandler(logger, cfg.ProbeAllowedHosts))
+		}
+
+		// Abrupt disconnects, only when explicitly enabled
+		if cfg.EnableResetEndpoint {
+			service.Get("/reset", apiHandlers.Reset)
+		}
+		// Deliberate panics, only when explicitly enabled and for admins
+		if cfg.EnablePanicEndpoint {
+			service.With(BearerTokenAuthMiddleware(tokens)).Get("/panic", apiHandlers.Panic)
+		}
+		// Work endpoint, optionally rejecting unknown query parameters and
+		// capping concurrent streams; POST additionally echoes a response template
+		var workMiddlewares chi.Middlewares
+		if cfg.StrictQueryParams {
+			workMiddlewares = append(workMiddlewares, StrictQueryParamsMiddleware(workQueryParams))
+		}
+		if cfg.MaxStreamConnections > 0 {
+			workMiddlewares = append(workMiddlewares, StreamOnly(StreamLimitMiddleware(metricsRegistry, cfg.MaxStreamConnections)))
+		}
+		work.With(workMiddlewares...).Get("/work", apiHandlers.Work)
+		work.With(workMiddlewares...).Post("/work", apiHandlers.Work)
+
+		// Many concurrent work jobs from one call
+		work.Post("/work/batch", apiHandlers.BatchWork)
+
+		// Go version, build settings and dependencies of the binary
+		service.Get("/buildinfo", BuildInfo)
+
+		// Middleware chain applied to API routes, for debugging ordering issues
+		service.Get("/debug/middleware", func(w http.ResponseWriter, r *http.Request) {
+			MiddlewareChainHandler(chain)(w, r)
 		})
+
+		// Every registered method and route pattern, for API discovery
+		service.Get("/routes", RoutesHandler(root))
+
+		// Audit log of admin actions
+		api.With(BearerTokenAuthMiddleware(tokens)).Get("/audit", audit.Handler(auditLog))
+
+		// Admin routes with bearer token authentication. Only allowlisted
+		// networks reach them, checked before the token.
+		admin := api.With()
+		if len(cfg.AdminAllowedCIDRs) > 0 {
+			use(admin, scopeAdmin, "IPAllowlistMiddleware", IPAllowlistMiddleware(cfg.AdminAllowedCIDRs, cfg.TrustProxy))
+		}
+		use(admin, scopeAdmin, "BearerTokenAuthMiddleware", BearerTokenAuthMiddleware(tokens))
+		if cfg.MaxBodyBytes > 0 {
+			use(admin, scopeAdmin, "MaxBodyBytesMiddleware", MaxBodyBytesMiddleware(cfg.MaxBodyBytes))
+		}
+		use(admin, scopeAdmin, "AuditMiddleware", AuditMiddleware(auditLog))
+
+		admin.Get("/toggles/error-rate", toggleHandlers.GetErrorRate)
+		admin.Post("/toggles/error-rate", toggleHandlers.ErrorRate)
+		admin.Post("/toggles/latency", toggleHandlers.Latency)
+		admin.Post("/toggles/memory", toggleHandlers.Memory)
+		admin.Post("/toggles/readiness", healthHandlers.ToggleReadiness)
+		admin.Post("/toggles/deadlock", healthHandlers.ToggleDeadlock)
+
+		// Chaos scenarios built on the toggles
+		admin.Post("/chaos/latency-ramp", toggleHandlers.LatencyRamp)
+
+		// Admin routes for managing the service itself
+		admin.Post("/admin/token", adminHandlers.RotateToken)
+		admin.Post("/loglevel", LogLevelHandler(logger, logLevels))
+
+		// On-demand CPU profile capture, bounded by its own ?seconds= rather
+		// than REQUEST_TIMEOUT
+		api.WithoutTimeout().With(BearerTokenAuthMiddleware(tokens)).Get("/profile/cpu", adminHandlers.CPUProfile)
 	})
 
+	// Error injection can only be scoped to routes that exist
+	toggleHandlers.knownRoutes = routePatterns(r)
+
 	return r
-}
\ No newline at end of file
+}
+
+// timedRoutes registers routes on a group running under a timeout or, for
+// route patterns listed in TIMEOUT_EXEMPT_ROUTES, on a group without one, so
+// long-lived streams such as SSE are not cut off
+type timedRoutes struct {
+	prefix  string
+	timed   chi.Router
+	untimed chi.Router
+	exempt  map[string]bool
+}
+
+// newTimedRoutes creates a group on r under timeout and one without it;
+// prefix is the path r is mounted at
+func newTimedRoutes(r chi.Router, prefix string, timeout time.Duration, exempt map[string]bool) timedRoutes {
+	timed := r.Group(nil)
+	timed.Use(TimeoutMiddleware(timeout))
+
+	return timedRoutes{prefix: prefix, timed: timed, untimed: r.Group(nil), exempt: exempt}
+}
+
+// Use adds middlewares after the timeout for routes registered from now on
+func (t timedRoutes) Use(middlewares ...func(http.Handler) http.Handler) {
+	t.timed.Use(middlewares...)
+	t.untimed.Use(middlewares...)
+}
+
+// With returns a copy of the routes with middlewares added after the timeout
+func (t timedRoutes) With(middlewares ...func(http.Handler) http.Handler) timedRoutes {
+	t.timed = t.timed.With(middlewares...)
+	t.untimed = t.untimed.With(middlewares...)
+	return t
+}
+
+// WithoutTimeout returns the group without a timeout, for routes that bound
+// their own duration
+func (t timedRoutes) WithoutTimeout() chi.Router {
+	return t.untimed
+}
+
+// Get registers a GET route on the group matching its timeout exemption
+func (t timedRoutes) Get(pattern string, handler http.HandlerFunc) {
+	t.group(pattern).Get(pattern, handler)
+}
+
+// Post registers a POST route on the group matching its timeout exemption
+func (t timedRoutes) Post(pattern string, handler http.HandlerFunc) {
+	t.group(pattern).Post(pattern, handler)
+}
+
+// Handle registers a route for every method on the group matching its
+// timeout exemption
+func (t timedRoutes) Handle(pattern string, handler http.Handler) {
+	t.group(pattern).Handle(pattern, handler)
+}
+
+// group returns the untimed group for exempt patterns and the timed one otherwise
+func (t timedRoutes) group(pattern string) chi.Router {
+	if t.exempt[t.prefix+pattern] {
+		return t.untimed
+	}
+	return t.timed
+}
+
+// Scopes reported for each middleware in the chain
+const (
+	scopeGlobal   = "global"
+	scopeAPI      = "/api/v1"
+	scopeNonAdmin = "/api/v1 non-admin"
+	scopeAdmin    = "/api/v1 admin"
+)
+
+// middlewareEntry is one middleware in the chain and the routes it applies to
+type middlewareEntry struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// middlewareUser is a router or group of routes that middleware is added to
+type middlewareUser interface {
+	Use(middlewares ...func(http.Handler) http.Handler)
+}
+
+// routeGroups adds middleware to several groups of routes at once
+type routeGroups []middlewareUser
+
+// Use adds middlewares to every group
+func (g routeGroups) Use(middlewares ...func(http.Handler) http.Handler) {
+	for _, group := range g {
+		group.Use(middlewares...)
+	}
+}
+
+// pathOrDefault returns the configured route path, or fallback when unset
+func pathOrDefault(path, fallback string) string {
+	if path == "" {
+		return fallback
+	}
+	return path
+}
+
+// routePatterns returns the set of route patterns registered on the router
+func routePatterns(r chi.Routes) map[string]bool {
+	patterns := make(map[string]bool)
+	chi.Walk(r, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		patterns[route] = true
+		return nil
+	})
+	return patterns
+}
@@ -0,0 +1,1321 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitoring-dashboard-automation/internal/config"
+	"monitoring-dashboard-automation/internal/health"
+	"monitoring-dashboard-automation/internal/logging"
+	"monitoring-dashboard-automation/internal/metrics"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestRouter builds the full router with a no-op logger and fresh dependencies
+func newTestRouter(cfg *config.Config) http.Handler {
+	return NewRouter(cfg, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metrics.NewRegistry(), health.NewChecker(), nil, nil)
+}
+
+func TestNewRouter_MetricsPublicByDefault(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestNewRouter_MountsRealHandlers(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	tests := []struct {
+		method   string
+		path     string
+		contains string
+	}{
+		{method: "GET", path: "/metrics", contains: "go_goroutines"},
+		{method: "GET", path: "/healthz", contains: "OK"},
+		{method: "GET", path: "/readyz", contains: "Ready"},
+		{method: "GET", path: "/api/v1/ping", contains: `"message":"pong"`},
+		{method: "GET", path: "/api/v1/toggles/error-rate", contains: `"enabled"`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, http.StatusOK, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), tt.contains) {
+			t.Errorf("%s %s: expected body containing %s, got %q", tt.method, tt.path, tt.contains, w.Body.String())
+		}
+	}
+}
+
+func TestNewRouter_RecordsRequestMetrics(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/ping", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	expected := `http_requests_total{method="GET",route="/api/v1/ping",status="200"} 1`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("Expected %s in metrics output", expected)
+	}
+}
+
+func TestNewRouter_ProtectedMetrics(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", ProtectMetrics: true})
+
+	// Without a token the scrape is rejected
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// With the admin token the scrape succeeds
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w = httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d with token, got %d", http.StatusOK, w.Code)
+	}
+
+	if !contains(w.Body.String(), "http_requests_total") {
+		t.Error("Expected metrics body when authorized")
+	}
+}
+
+func TestNewRouter_AuditLogRecordsErrorRateChange(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	body := `{"enabled": true, "rate": 0.25, "status_code": 503}`
+	req := httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// The audit endpoint requires the admin token as well
+	req = httptest.NewRequest("GET", "/api/v1/audit", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/audit", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	auditBody := w.Body.String()
+	if strings.Contains(auditBody, "test-token") {
+		t.Error("Audit log must not expose the admin token")
+	}
+
+	var response struct {
+		Entries []struct {
+			Action    string                 `json:"action"`
+			TokenName string                 `json:"token_name"`
+			Params    map[string]interface{} `json:"params"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(auditBody), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Entries) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d", len(response.Entries))
+	}
+
+	entry := response.Entries[0]
+	if entry.Action != "POST /api/v1/toggles/error-rate" {
+		t.Errorf("Expected error-rate action, got %s", entry.Action)
+	}
+	if entry.TokenName != adminTokenName("test-token") {
+		t.Errorf("Expected token name %s, got %s", adminTokenName("test-token"), entry.TokenName)
+	}
+	if entry.Params["rate"] != 0.25 {
+		t.Errorf("Expected rate 0.25 in params, got %v", entry.Params["rate"])
+	}
+}
+
+func TestNewRouter_AuditLogTellsTokensApart(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminTokens: []string{"old-token", "new-token"}})
+
+	for _, token := range []string{"old-token", "new-token"} {
+		req := httptest.NewRequest("POST", "/api/v1/toggles/readiness", strings.NewReader(`{"force_failure": false}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/audit", nil)
+	req.Header.Set("Authorization", "Bearer new-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Entries []struct {
+			TokenName string `json:"token_name"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(response.Entries))
+	}
+
+	names := map[string]bool{response.Entries[0].TokenName: true, response.Entries[1].TokenName: true}
+	if !names[adminTokenName("old-token")] || !names[adminTokenName("new-token")] {
+		t.Errorf("Expected entries for both tokens, got %v", names)
+	}
+	if strings.Contains(w.Body.String(), "old-token") || strings.Contains(w.Body.String(), "new-token") {
+		t.Error("Audit log must not expose the admin tokens")
+	}
+}
+
+func TestNewRouter_StrictQueryParams(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", StrictQueryParams: true})
+
+	req := httptest.NewRequest("GET", "/api/v1/work?ms2=100", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for unknown param, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "ms2") {
+		t.Errorf("Expected unknown key in body, got %q", w.Body.String())
+	}
+
+	// Without strict mode typos are ignored as before
+	router = newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req = httptest.NewRequest("GET", "/api/v1/work?ms2=100", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code == http.StatusBadRequest {
+		t.Error("Expected unknown params to be accepted in permissive mode")
+	}
+}
+
+func TestNewRouter_RotateAdminToken(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "old-token"})
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/token", strings.NewReader(`{"token": "new-token"}`))
+	req.Header.Set("Authorization", "Bearer old-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	readiness := `{"force_failure": false}`
+
+	// The old token is rejected after rotation
+	req = httptest.NewRequest("POST", "/api/v1/toggles/readiness", strings.NewReader(readiness))
+	req.Header.Set("Authorization", "Bearer old-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for old token, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// The new token is accepted
+	req = httptest.NewRequest("POST", "/api/v1/toggles/readiness", strings.NewReader(readiness))
+	req.Header.Set("Authorization", "Bearer new-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for new token, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestNewRouter_MiddlewareChain(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/debug/middleware", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Middleware []middlewareEntry `json:"middleware"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	expected := []middlewareEntry{
+		{Name: "middleware.RequestID", Scope: "global"},
+		{Name: "RequestIDMiddleware", Scope: "global"},
+		{Name: "JSONEncodeErrorMiddleware", Scope: "global"},
+		{Name: "LoggingMiddleware", Scope: "global"},
+		{Name: "PrometheusMiddleware", Scope: "global"},
+		{Name: "PanicRecoveryMiddleware", Scope: "global"},
+		{Name: "TimeoutMiddleware", Scope: "/api/v1"},
+		{Name: "LatencyInjectionMiddleware", Scope: "/api/v1 non-admin"},
+		{Name: "ErrorInjectionMiddleware", Scope: "/api/v1 non-admin"},
+		{Name: "HeaderErrorInjectionMiddleware", Scope: "/api/v1 non-admin"},
+		{Name: "BearerTokenAuthMiddleware", Scope: "/api/v1 admin"},
+		{Name: "AuditMiddleware", Scope: "/api/v1 admin"},
+	}
+	if !reflect.DeepEqual(response.Middleware, expected) {
+		t.Errorf("Expected middleware chain %v, got %v", expected, response.Middleware)
+	}
+}
+
+func TestNewRouter_MiddlewareChainScopes(t *testing.T) {
+	router := newTestRouter(&config.Config{
+		AdminToken:         "test-token",
+		AdminAllowedCIDRs:  []string{"192.0.2.0/24"},
+		MaxBodyBytes:       1024,
+		CORSAllowedOrigins: []string{"https://example.com"},
+		RateLimitRPS:       100,
+		RateLimitBurst:     100,
+		IdempotencyTTL:     time.Minute,
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/debug/middleware", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Middleware []middlewareEntry `json:"middleware"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	scopes := make(map[string]string)
+	for _, entry := range response.Middleware {
+		scopes[entry.Name] = entry.Scope
+	}
+	for name, scope := range map[string]string{
+		"LoggingMiddleware":         "global",
+		"CORSMiddleware":            "/api/v1",
+		"RateLimitMiddleware":       "/api/v1",
+		"IdempotencyMiddleware":     "/api/v1 non-admin",
+		"IPAllowlistMiddleware":     "/api/v1 admin",
+		"BearerTokenAuthMiddleware": "/api/v1 admin",
+		"MaxBodyBytesMiddleware":    "/api/v1 admin",
+		"AuditMiddleware":           "/api/v1 admin",
+	} {
+		if scopes[name] != scope {
+			t.Errorf("Expected %s in scope %q, got %q", name, scope, scopes[name])
+		}
+	}
+}
+
+func TestNewRouter_IdempotencyKeyDoesNotBypassAuth(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", IdempotencyTTL: time.Minute})
+
+	req := httptest.NewRequest("GET", "/api/v1/toggles/error-rate", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set(IdempotencyKeyHeader, "abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d with the token, got %d", http.StatusOK, w.Code)
+	}
+
+	// Reusing the admin's key without a token must not replay its response
+	req = httptest.NewRequest("GET", "/api/v1/toggles/error-rate", nil)
+	req.Header.Set(IdempotencyKeyHeader, "abc")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without the token, got %d: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+}
+
+func TestNewRouter_Routes(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/routes", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Routes []struct {
+			Method  string `json:"method"`
+			Pattern string `json:"pattern"`
+		} `json:"routes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	listed := make(map[string]bool)
+	for _, route := range response.Routes {
+		listed[route.Method+" "+route.Pattern] = true
+	}
+	for _, expected := range []string{
+		"GET /healthz",
+		"GET /api/v1/ping",
+		"GET /api/v1/work",
+		"POST /api/v1/work",
+		"POST /api/v1/toggles/error-rate",
+		"POST /api/v1/toggles/latency",
+		"GET /api/v1/routes",
+	} {
+		if !listed[expected] {
+			t.Errorf("Expected %s in the route listing, got %v", expected, response.Routes)
+		}
+	}
+}
+
+func TestNewRouter_BuildInfo(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/buildinfo", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		GoVersion string `json:"go_version"`
+		Main      struct {
+			Path string `json:"path"`
+		} `json:"main"`
+		Dependencies []struct {
+			Path    string `json:"path"`
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Main.Path != "monitoring-dashboard-automation" {
+		t.Errorf("Expected main module monitoring-dashboard-automation, got %q", response.Main.Path)
+	}
+	if response.GoVersion == "" {
+		t.Error("Expected the Go version to be reported")
+	}
+
+	found := false
+	for _, dep := range response.Dependencies {
+		if dep.Path == "github.com/go-chi/chi/v5" && dep.Version != "" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected github.com/go-chi/chi/v5 among dependencies, got %v", response.Dependencies)
+	}
+}
+
+func TestNewRouter_MiddlewareChainWithBodySampling(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", LogBodySampleRate: 0.5, LogBodyMaxBytes: 128})
+
+	req := httptest.NewRequest("GET", "/api/v1/debug/middleware", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), `{"name":"LoggingMiddleware","scope":"global"},{"name":"BodySamplingMiddleware","scope":"global"},{"name":"PrometheusMiddleware","scope":"global"}`) {
+		t.Errorf("Expected body sampling between logging and Prometheus, got %s", w.Body.String())
+	}
+}
+
+func TestNewRouter_CPUProfileRequiresToken(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/profile/cpu?seconds=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestNewRouter_CPUProfileOutlivesRequestTimeout(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", RequestTimeout: 100 * time.Millisecond})
+
+	req := httptest.NewRequest("GET", "/api/v1/profile/cpu?seconds=1", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected a profile longer than REQUEST_TIMEOUT to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("Expected a pprof download, got %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestNewRouter_ToggleAuthAndInjection(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	body := `{"enabled": true, "rate": 1.0, "status_code": 502}`
+
+	// Toggles are behind the admin token
+	req := httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/ping", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected ping to be unaffected by the rejected toggle, got %d", w.Code)
+	}
+
+	// An enabled toggle injects errors into API routes
+	req = httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/ping", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Expected injected status %d on ping, got %d", http.StatusBadGateway, w.Code)
+	}
+}
+
+func TestNewRouter_InjectionCanBeDisabledAtFullRate(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	post := func(path, body string, header http.Header) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Every non-admin request fails once injection runs at rate 1.0
+	if code := post("/api/v1/toggles/error-rate", `{"enabled": true, "rate": 1.0, "status_code": 503}`, nil); code != http.StatusOK {
+		t.Fatalf("Expected status %d enabling injection, got %d", http.StatusOK, code)
+	}
+	if code := post("/api/v1/toggles/latency", `{"enabled": true, "min_ms": 0, "max_ms": 0}`, nil); code != http.StatusOK {
+		t.Fatalf("Expected latency toggle to be reachable during injection, got %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected injected status %d on ping, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// Admin routes are never injected into, even on request
+	header := http.Header{InjectErrorHeader: []string{"500"}}
+	if code := post("/api/v1/toggles/error-rate", `{"enabled": false, "rate": 0, "status_code": 500}`, header); code != http.StatusOK {
+		t.Fatalf("Expected status %d disabling injection, got %d", http.StatusOK, code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected ping to recover after disabling injection, got %d", w.Code)
+	}
+}
+
+func TestNewRouter_ScopedErrorInjection(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	body := `{"enabled": true, "rate": 1.0, "status_code": 503, "routes": ["/api/v1/debug/middleware"]}`
+	req := httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// The scoped route fails
+	req = httptest.NewRequest("GET", "/api/v1/debug/middleware", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected scoped route to return %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// Other routes stay clean
+	for _, path := range []string{"/api/v1/ping", "/healthz", "/metrics"} {
+		req = httptest.NewRequest("GET", path, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected %s to return %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+
+	// Unknown route patterns are rejected
+	body = `{"enabled": true, "rate": 1.0, "status_code": 503, "routes": ["/api/v1/missing"]}`
+	req = httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for unknown route, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestNewRouter_GetErrorRate(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/toggles/error-rate", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/toggles/error-rate", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"status_code":500`) {
+		t.Errorf("Expected default status code in body, got %s", w.Body.String())
+	}
+}
+
+func TestNewRouter_StartupProbe(t *testing.T) {
+	checker := health.NewChecker()
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metrics.NewRegistry(), checker, nil, nil)
+
+	req := httptest.NewRequest("GET", "/startupz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d before startup, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	checker.MarkStarted()
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d after startup, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestNewRouter_MethodNotAllowed(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/toggles/latency", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST" {
+		t.Errorf("Expected Allow: POST, got %q", allow)
+	}
+	if w.Header().Get("WWW-Authenticate") != "" {
+		t.Error("Expected no WWW-Authenticate header on a 405")
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", w.Header().Get("Content-Type"))
+	}
+
+	var body errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Status != http.StatusMethodNotAllowed || body.Error == "" {
+		t.Errorf("Unexpected error body: %+v", body)
+	}
+
+	// Routes serving several methods list all of them
+	req = httptest.NewRequest("DELETE", "/api/v1/toggles/error-rate", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("Expected Allow: GET, POST, got %q", allow)
+	}
+}
+
+func TestNewRouter_JSONNotFound(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, health.NewChecker(), nil, nil)
+
+	for _, path := range []string{"/does-not-exist", "/api/v1/does-not-exist"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: expected JSON content type, got %q", path, w.Header().Get("Content-Type"))
+		}
+
+		var body errorResponse
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", path, err)
+		}
+		if body.Status != http.StatusNotFound || body.Error == "" || body.RequestID == "" {
+			t.Errorf("%s: unexpected error body: %+v", path, body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `http_requests_total{method="GET",route="unmatched",status="404"} 2`) {
+		t.Error("Expected the 404s under the \"unmatched\" route")
+	}
+}
+
+func TestNewRouter_JSONMethodNotAllowed(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	for _, tt := range []struct {
+		path  string
+		allow string
+	}{
+		{path: "/api/v1/ping", allow: "GET"},
+		{path: "/healthz", allow: "GET"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, nil))
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, http.StatusMethodNotAllowed, w.Code)
+		}
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: expected JSON content type, got %q", tt.path, w.Header().Get("Content-Type"))
+		}
+		if allow := w.Header().Get("Allow"); allow != tt.allow {
+			t.Errorf("%s: expected Allow: %s, got %q", tt.path, tt.allow, allow)
+		}
+
+		var body errorResponse
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.path, err)
+		}
+		if body.Status != http.StatusMethodNotAllowed || body.Error == "" {
+			t.Errorf("%s: unexpected error body: %+v", tt.path, body)
+		}
+	}
+}
+
+func TestNewRouter_AdminAllowlist(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", AdminAllowedCIDRs: []string{"10.0.0.0/8"}})
+
+	toggle := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/api/v1/toggles/error-rate", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The allowlist is checked before the token
+	if code := toggle("192.168.1.10:4000"); code != http.StatusForbidden {
+		t.Errorf("Expected status %d from outside the allowlist, got %d", http.StatusForbidden, code)
+	}
+	if code := toggle("10.1.2.3:4000"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without a token from inside the allowlist, got %d", http.StatusUnauthorized, code)
+	}
+
+	// Public routes are unaffected
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected ping to stay public, got %d", w.Code)
+	}
+}
+
+func TestNewRouter_CORSPreflight(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", CORSAllowedOrigins: []string{"*"}})
+
+	req := httptest.NewRequest("OPTIONS", "/api/v1/ping", nil)
+	req.Header.Set("Origin", "https://tools.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected CORS headers on /api/v1, got %v", w.Header())
+	}
+
+	// Routes outside /api/v1 are not covered
+	req = httptest.NewRequest("GET", "/healthz", nil)
+	req.Header.Set("Origin", "https://tools.example.com")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected no CORS headers outside /api/v1")
+	}
+}
+
+func TestNewRouter_DynamicChecksCount(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	checker := health.NewChecker()
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, checker, nil, nil)
+
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+
+	checker.AddCheck("database", func(ctx context.Context) error { return nil })
+	checker.AddCheck("cache", func(ctx context.Context) error { return nil })
+	if !strings.Contains(scrape(), "dynamic_checks_count 2") {
+		t.Error("Expected dynamic_checks_count 2 after adding two checks")
+	}
+
+	checker.RemoveCheck("cache")
+	if !strings.Contains(scrape(), "dynamic_checks_count 1") {
+		t.Error("Expected dynamic_checks_count 1 after removing a check")
+	}
+}
+
+func TestNewRouter_ReadinessMetrics(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	checker := health.NewChecker()
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, checker, nil, nil)
+
+	var failing atomic.Bool
+	checker.AddCheck("database", func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	probe := func() string {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+
+	if !strings.Contains(probe(), "readiness_up 1") {
+		t.Error("Expected readiness_up 1 while checks pass")
+	}
+
+	failing.Store(true)
+	body := probe()
+	if !strings.Contains(body, "readiness_up 0") {
+		t.Error("Expected readiness_up 0 after a check fails")
+	}
+	if !strings.Contains(body, `readiness_check_failures_total{component="database"} 1`) {
+		t.Error("Expected the database failure to be counted")
+	}
+
+	failing.Store(false)
+	if !strings.Contains(probe(), "readiness_up 1") {
+		t.Error("Expected readiness_up 1 after the check recovers")
+	}
+}
+
+func TestNewRouter_ReadinessMetricsIgnoreCachedProbes(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	checker := health.NewChecker()
+	checker.SetCacheTTL(time.Hour)
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, checker, nil, nil)
+
+	checker.AddCheck("database", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `readiness_check_failures_total{component="database"} 1`) {
+		t.Errorf("Expected only the check run to be counted, not the cached probes:\n%s", w.Body.String())
+	}
+}
+
+func TestNewRouter_ResetEndpoint(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(&config.Config{AdminToken: "test-token", EnableResetEndpoint: true}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/reset")
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("Expected a connection error, got status %d", resp.StatusCode)
+	}
+
+	// The rest of the API is unaffected
+	resp, err = http.Get(server.URL + "/api/v1/ping")
+	if err != nil {
+		t.Fatalf("Expected ping to succeed, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestNewRouter_ResetEndpointDisabled(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/reset", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d when disabled, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestNewRouter_PanicEndpoint(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	cfg := &config.Config{AdminToken: "test-token", EnablePanicEndpoint: true}
+	router := NewRouter(cfg, zap.New(core), logging.NewLevels(zapcore.InfoLevel), metrics.NewRegistry(), health.NewChecker(), nil, nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path, token string) int {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("/api/v1/panic", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without a token, got %d", http.StatusUnauthorized, code)
+	}
+
+	if code := get("/api/v1/panic", "test-token"); code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d from a recovered panic, got %d", http.StatusInternalServerError, code)
+	}
+
+	entries := logs.FilterMessage("Panic recovered").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one panic log entry, got %d", len(entries))
+	}
+	if stack, _ := entries[0].ContextMap()["stack"].(string); !strings.Contains(stack, "Panic") {
+		t.Errorf("Expected the stack trace to be logged, got %q", stack)
+	}
+
+	// The server keeps serving after the panic
+	if code := get("/api/v1/ping", ""); code != http.StatusOK {
+		t.Errorf("Expected ping to succeed after the panic, got %d", code)
+	}
+}
+
+func TestNewRouter_RecoveredPanicCounted(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	cfg := &config.Config{AdminToken: "test-token", EnablePanicEndpoint: true}
+	router := NewRouter(cfg, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, health.NewChecker(), nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/panic", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	var response errorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected a JSON error, got %q: %v", w.Body.String(), err)
+	}
+	if response.RequestID == "" || response.RequestID != w.Header().Get("X-Request-ID") {
+		t.Errorf("Expected request_id %q, got %q", w.Header().Get("X-Request-ID"), response.RequestID)
+	}
+
+	// The 5xx alert is built on http_requests_total, so panics must show up there
+	w = httptest.NewRecorder()
+	metricsRegistry.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	expected := `http_requests_total{method="GET",route="/api/v1/panic",status="500"} 1`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("Expected %s in metrics", expected)
+	}
+}
+
+func TestNewRouter_PanicEndpointDisabled(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	req := httptest.NewRequest("GET", "/api/v1/panic", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d when disabled, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestNewRouter_PerRouteTimeouts(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	cfg := &config.Config{
+		AdminToken:     "test-token",
+		RequestTimeout: 50 * time.Millisecond,
+		WorkTimeout:    time.Second,
+	}
+	router := NewRouter(cfg, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, health.NewChecker(), nil, nil)
+	
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	
+	// Delay every API request past REQUEST_TIMEOUT but well within WORK_TIMEOUT
+	req := httptest.NewRequest("POST", "/api/v1/toggles/latency", strings.NewReader(`{"enabled": true, "min_ms": 100, "max_ms": 100}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected latency toggle to be set, got %d", w.Code)
+	}
+	
+	start := time.Now()
+	if w := get("/api/v1/ping"); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected ping to time out with %d, got %d", http.StatusGatewayTimeout, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("Expected ping to be cut off at REQUEST_TIMEOUT, took %v", elapsed)
+	}
+	
+	if w := get("/api/v1/work?ms=100&jitter=0"); w.Code != http.StatusOK {
+		t.Errorf("Expected work to be allowed more time, got %d: %s", w.Code, w.Body.String())
+	}
+	
+	// Work past WORK_TIMEOUT is cancelled through its context
+	start = time.Now()
+	if w := get("/api/v1/work?ms=5000&jitter=0"); w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected work past WORK_TIMEOUT to be cancelled with %d, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*time.Second {
+		t.Errorf("Expected work to stop at WORK_TIMEOUT, took %v", elapsed)
+	}
+	if cancelled := findCounter(t, metricsRegistry, "work_cancelled_total"); cancelled != 1 {
+		t.Errorf("Expected one cancelled work job, got %v", cancelled)
+	}
+}
+
+func TestNewRouter_TimeoutExemptRoutes(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	cfg := &config.Config{
+		RequestTimeout:      50 * time.Millisecond,
+		WorkTimeout:         50 * time.Millisecond,
+		TimeoutExemptRoutes: []string{"/api/v1/work"},
+	}
+	router := NewRouter(cfg, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, health.NewChecker(), nil, nil)
+	
+	// Exemption is by route pattern, so the query string does not matter
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/work?ms=150&jitter=0", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected exempt route to outlive WORK_TIMEOUT, got %d: %s", w.Code, w.Body.String())
+	}
+	
+	// Routes under the same prefix that are not exempt keep their timeout
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/work/batch", strings.NewReader(`{"count": 1, "ms": 150, "jitter": 0}`)))
+	if w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected non-exempt batch work to be cancelled with %d, got %d: %s", http.StatusRequestTimeout, w.Code, w.Body.String())
+	}
+}
+
+func TestNewRouter_DefaultTimeoutAllowsShortRequests(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.RequestTimeout < time.Second || cfg.WorkTimeout < time.Second {
+		t.Fatalf("Expected default timeouts of seconds or more, got %v and %v", cfg.RequestTimeout, cfg.WorkTimeout)
+	}
+	
+	metricsRegistry := metrics.NewRegistry()
+	router := NewRouter(cfg, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, health.NewChecker(), nil, nil)
+	
+	// Handlers that take a little while are not cancelled prematurely
+	for _, path := range []string{"/api/v1/work?ms=50&jitter=0", "/api/v1/ping"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	if cancelled := findCounter(t, metricsRegistry, "work_cancelled_total"); cancelled != 0 {
+		t.Errorf("Expected no cancelled work, got %v", cancelled)
+	}
+}
+
+func TestNewRouter_TimeoutCancelsLongRequests(t *testing.T) {
+	t.Setenv("WORK_TIMEOUT", "100ms")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	
+	router := newTestRouter(cfg)
+	
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/work?ms=2000&jitter=0", nil))
+	
+	if w.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected status %d once the timeout passed, got %d", http.StatusRequestTimeout, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Expected the request to be cancelled at the timeout, took %v", elapsed)
+	}
+}
+
+func TestNewRouter_CustomProbePaths(t *testing.T) {
+	router := newTestRouter(&config.Config{
+		AdminToken:    "test-token",
+		LivenessPath:  "/live",
+		ReadinessPath: "/health/ready",
+		MetricsPath:   "/internal/metrics",
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/live", wantStatus: http.StatusOK},
+		{path: "/health/ready", wantStatus: http.StatusOK},
+		{path: "/internal/metrics", wantStatus: http.StatusOK},
+		{path: "/healthz", wantStatus: http.StatusNotFound},
+		{path: "/readyz", wantStatus: http.StatusNotFound},
+		{path: "/metrics", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.wantStatus, w.Code)
+		}
+	}
+}
+
+func TestNewRouter_UnmatchedRouteLabel(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	for _, path := range []string{"/wp-admin/setup.php", "/.env", "/random/scan/path"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	if !strings.Contains(body, `http_requests_total{method="GET",route="unmatched",status="404"} 3`) {
+		t.Error("Expected unmatched requests to share the \"unmatched\" route label")
+	}
+	if strings.Contains(body, "wp-admin") || strings.Contains(body, "/random/scan/path") {
+		t.Error("Expected raw paths to be kept out of metric labels")
+	}
+}
+
+func TestNewRouter_ProbesNotCached(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token"})
+
+	for _, path := range []string{"/metrics", "/healthz", "/readyz", "/startupz"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("%s: expected Cache-Control no-store, got %q", path, got)
+		}
+	}
+
+	// API responses are left alone
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/ping", nil))
+	if got := w.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Expected no Cache-Control on API routes, got %q", got)
+	}
+}
+
+func TestNewRouter_ComponentLogLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := logging.NewLevels(zapcore.InfoLevel)
+	logger := zap.New(levels.Wrap(core))
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, logger, levels, metrics.NewRegistry(), health.NewChecker(), nil, nil)
+
+	work := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/work?ms=1", nil))
+	}
+
+	work()
+	if logs.FilterMessage("Starting work").Len() != 0 {
+		t.Fatal("Expected no http debug logs at the default info level")
+	}
+
+	// The endpoint requires the admin token
+	req := httptest.NewRequest("POST", "/api/v1/loglevel", strings.NewReader(`{"component": "http", "level": "debug"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d without a token, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/loglevel", strings.NewReader(`{"component": "http", "level": "debug"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	work()
+	if logs.FilterMessage("Starting work").Len() != 1 {
+		t.Error("Expected http debug logs once the http component is at debug")
+	}
+	if levels.Level("health") != zapcore.InfoLevel {
+		t.Errorf("Expected other components to stay at info, got %v", levels.Level("health"))
+	}
+}
+
+func TestNewRouter_ReadinessOutageMetric(t *testing.T) {
+	metricsRegistry := metrics.NewRegistry()
+	checker := health.NewChecker()
+	router := NewRouter(&config.Config{AdminToken: "test-token"}, zap.NewNop(), logging.NewLevels(zapcore.InfoLevel), metricsRegistry, checker, nil, nil)
+
+	probe := func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+	}
+
+	checker.SetForceFailure(true)
+	probe()
+	time.Sleep(200 * time.Millisecond)
+	checker.SetForceFailure(false)
+	probe()
+
+	families, err := metricsRegistry.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "readiness_outage_seconds" {
+			continue
+		}
+		histogram := family.GetMetric()[0].GetHistogram()
+		if histogram.GetSampleCount() != 1 {
+			t.Fatalf("Expected one outage, got %d", histogram.GetSampleCount())
+		}
+		if sum := histogram.GetSampleSum(); sum < 0.2 || sum > 1 {
+			t.Errorf("Expected an outage of about 0.2s, got %vs", sum)
+		}
+		return
+	}
+	t.Fatal("Metric readiness_outage_seconds not found")
+}
+
+func TestNewRouter_ToggleBodyTooLarge(t *testing.T) {
+	router := newTestRouter(&config.Config{AdminToken: "test-token", MaxBodyBytes: 64})
+
+	body := `{"enabled": true, "rate": 0.5, "status_code": 503, "padding": "` + strings.Repeat("x", 64) + `"}`
+	req := httptest.NewRequest("POST", "/api/v1/toggles/error-rate", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
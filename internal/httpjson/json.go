@@ -0,0 +1,44 @@
+// Package httpjson writes JSON response bodies for every HTTP handler in the
+// service, so encode failures are reported the same way wherever they happen.
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// ErrorReporter is told about a response body that failed to encode
+type ErrorReporter func(r *http.Request, err error)
+
+type reporterKey struct{}
+
+// WithErrorReporter returns a copy of ctx whose JSON responses report encode
+// failures to report
+func WithErrorReporter(ctx context.Context, report ErrorReporter) context.Context {
+	return context.WithValue(ctx, reporterKey{}, report)
+}
+
+// Write sends v as a JSON body with the given status code, indented when the
+// request has ?pretty=true. Encode failures go to the request's ErrorReporter.
+func Write(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := newEncoder(w, r).Encode(v); err != nil {
+		if report, ok := r.Context().Value(reporterKey{}).(ErrorReporter); ok {
+			report(r, err)
+		}
+	}
+}
+
+// newEncoder returns an encoder for w that indents its output when the
+// request has ?pretty=true
+func newEncoder(w http.ResponseWriter, r *http.Request) *json.Encoder {
+	encoder := json.NewEncoder(w)
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder
+}
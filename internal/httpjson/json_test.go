@@ -0,0 +1,59 @@
+package httpjson
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// failingWriter accepts headers but fails every body write
+type failingWriter struct {
+	httptest.ResponseRecorder
+}
+
+func (f *failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken writer")
+}
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest("GET", "/status?pretty=true", nil)
+	w := httptest.NewRecorder()
+	
+	Write(w, req, http.StatusCreated, map[string]string{"status": "ok"})
+	
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "\n  \"status\": \"ok\"") {
+		t.Errorf("Expected an indented body for ?pretty=true, got %q", w.Body.String())
+	}
+}
+
+func TestWrite_ReportsEncodeErrors(t *testing.T) {
+	var reported []error
+	req := httptest.NewRequest("GET", "/status", nil)
+	req = req.WithContext(WithErrorReporter(req.Context(), func(r *http.Request, err error) {
+		reported = append(reported, err)
+	}))
+	
+	Write(&failingWriter{}, req, http.StatusOK, map[string]string{"status": "ok"})
+	
+	if len(reported) != 1 {
+		t.Fatalf("Expected 1 reported encode error, got %d", len(reported))
+	}
+	
+	// Values that cannot be encoded are reported as well
+	Write(httptest.NewRecorder(), req, http.StatusOK, map[string]interface{}{"bad": make(chan int)})
+	
+	if len(reported) != 2 {
+		t.Errorf("Expected unencodable values to be reported, got %d reports", len(reported))
+	}
+	
+	// Without a reporter, failures are dropped rather than panicking
+	Write(&failingWriter{}, httptest.NewRequest("GET", "/status", nil), http.StatusOK, "ok")
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels holds the minimum log level of each component, changeable at
+// runtime. A component is the first segment of a logger's name, so
+// logger.Named("http") and logger.Named("http").Named("audit") both belong to
+// "http". Loggers without a name, or with a component that has no level of
+// its own, use the default level.
+type Levels struct {
+	mu           sync.RWMutex
+	defaultLevel zapcore.Level
+	levels       map[string]zapcore.Level
+}
+
+// NewLevels creates a level set where every component logs at defaultLevel
+func NewLevels(defaultLevel zapcore.Level) *Levels {
+	return &Levels{
+		defaultLevel: defaultLevel,
+		levels:       make(map[string]zapcore.Level),
+	}
+}
+
+// Level returns the minimum level logged for component
+func (l *Levels) Level(component string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.levels[component]; ok {
+		return level
+	}
+	return l.defaultLevel
+}
+
+// SetLevel changes the minimum level logged for component
+func (l *Levels) SetLevel(component string, level zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.levels[component] = level
+}
+
+// minLevel returns the lowest level any component logs at
+func (l *Levels) minLevel() zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	min := l.defaultLevel
+	for _, level := range l.levels {
+		if level < min {
+			min = level
+		}
+	}
+	return min
+}
+
+// Wrap returns a core that drops entries below their component's level
+// before passing them to core. core itself should accept every level.
+func (l *Levels) Wrap(core zapcore.Core) zapcore.Core {
+	return &componentCore{Core: core, levels: l}
+}
+
+// componentCore filters entries by the level of the logger's component
+type componentCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+// Enabled reports whether any component may log at level; Check makes the
+// per-component decision once the logger name is known
+func (c *componentCore) Enabled(level zapcore.Level) bool {
+	return level >= c.levels.minLevel() && c.Core.Enabled(level)
+}
+
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	return &componentCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *componentCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levels.Level(component(entry.LoggerName)) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// component returns the first segment of a zap logger name
+func component(loggerName string) string {
+	if i := strings.IndexByte(loggerName, '.'); i >= 0 {
+		return loggerName[:i]
+	}
+	return loggerName
+}
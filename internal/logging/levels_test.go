@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevels_PerComponent(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := NewLevels(zapcore.InfoLevel)
+	logger := zap.New(levels.Wrap(core))
+	
+	httpLogger := logger.Named("http")
+	healthLogger := logger.Named("health")
+	
+	httpLogger.Debug("http debug before")
+	healthLogger.Info("health info")
+	if logs.Len() != 1 || logs.All()[0].Message != "health info" {
+		t.Fatalf("Expected only the info entry at the default level, got %v", logs.All())
+	}
+	
+	levels.SetLevel("http", zapcore.DebugLevel)
+	
+	httpLogger.Debug("http debug")
+	httpLogger.Named("audit").Debug("http sub-logger debug")
+	healthLogger.Debug("health debug")
+	logger.Debug("root debug")
+	
+	if logs.FilterMessage("http debug").Len() != 1 {
+		t.Error("Expected http debug logs once the http component is at debug")
+	}
+	if logs.FilterMessage("http sub-logger debug").Len() != 1 {
+		t.Error("Expected loggers named under http to follow its level")
+	}
+	if logs.FilterMessage("health debug").Len() != 0 || logs.FilterMessage("root debug").Len() != 0 {
+		t.Error("Expected other components to stay at info")
+	}
+}
+
+func TestLevels_RaiseAboveDefault(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	levels := NewLevels(zapcore.InfoLevel)
+	logger := zap.New(levels.Wrap(core))
+	
+	levels.SetLevel("http", zapcore.ErrorLevel)
+	
+	logger.Named("http").Warn("http warning")
+	logger.Named("health").Warn("health warning")
+	
+	if logs.FilterMessage("http warning").Len() != 0 {
+		t.Error("Expected http warnings to be dropped at error level")
+	}
+	if logs.FilterMessage("health warning").Len() != 1 {
+		t.Error("Expected health warnings at the default level")
+	}
+	if got := levels.Level("http"); got != zapcore.ErrorLevel {
+		t.Errorf("Expected http level error, got %v", got)
+	}
+}
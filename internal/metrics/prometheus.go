@@ -3,6 +3,7 @@ package metrics
 import (
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -17,14 +18,70 @@ type Registry struct {
 	// HTTP metrics
 	httpRequestsTotal    *prometheus.CounterVec
 	httpRequestDuration  *prometheus.HistogramVec
+	httpHandlerDuration  *prometheus.HistogramVec
+	httpRequestsByClient *prometheus.CounterVec
+	httpRequestSize      *prometheus.HistogramVec
+	httpResponseSize     *prometheus.HistogramVec
+	httpRoutesObserved   prometheus.Gauge
+	jsonEncodeErrors     *prometheus.CounterVec
+	injectedErrorsTotal  *prometheus.CounterVec
+	injectionRate        prometheus.Gauge
+	idempotentReplays    *prometheus.CounterVec
+	rateLimitedRequests  *prometheus.CounterVec
+	concurrencyRejected  *prometheus.CounterVec
+	streamConnections    prometheus.Gauge
+	panicsRecovered      prometheus.Counter
+	
+	// Readiness outcomes
+	readinessUp            prometheus.Gauge
+	readinessCheckFailures *prometheus.CounterVec
+	readinessOutage        prometheus.Histogram
+	
+	// Constant 1, labelled with the Go version and main module of the binary
+	buildInfo *prometheus.GaugeVec
+	
+	// Size of runtime-registered entities, to catch registration leaks
+	dynamicChecksCount prometheus.Gauge
+	
+	// Distinct route label values seen, to catch cardinality creep
+	routesMu       sync.Mutex
+	routesObserved map[string]struct{}
 	
 	// Work metrics (for future tasks)
-	workJobsInflight     prometheus.Gauge
-	workFailuresTotal    *prometheus.CounterVec
+	workJobsInflight   prometheus.Gauge
+	workJobsSnapshot   prometheus.Histogram
+	workFailuresTotal  *prometheus.CounterVec
+	workJitterApplied  prometheus.Histogram
+	workDuration       *prometheus.HistogramVec
+	workCompletedTotal prometheus.Counter
+	workCancelledTotal prometheus.Counter
+}
+
+// Option customizes a Registry created by NewRegistry
+type Option func(*options)
+
+// options holds the settings Option values adjust
+type options struct {
+	httpDurationBuckets []float64
+}
+
+// WithHTTPDurationBuckets sets the bucket boundaries of
+// http_request_duration_seconds. An empty slice keeps prometheus.DefBuckets.
+func WithHTTPDurationBuckets(buckets []float64) Option {
+	return func(o *options) {
+		if len(buckets) > 0 {
+			o.httpDurationBuckets = buckets
+		}
+	}
 }
 
 // NewRegistry creates a new metrics registry
-func NewRegistry() *Registry {
+func NewRegistry(opts ...Option) *Registry {
+	o := options{httpDurationBuckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	
 	registry := prometheus.NewRegistry()
 	
 	// Register default Go metrics
@@ -44,11 +101,155 @@ func NewRegistry() *Registry {
 		prometheus.HistogramOpts{
 			Name:    "http_request_duration_seconds",
 			Help:    "HTTP request duration in seconds",
-			Buckets: prometheus.DefBuckets,
+			Buckets: o.httpDurationBuckets,
+		},
+		[]string{"method", "route"},
+	)
+	
+	httpHandlerDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_handler_duration_seconds",
+			Help:    "HTTP request duration in seconds, excluding injected latency",
+			Buckets: o.httpDurationBuckets,
 		},
 		[]string{"method", "route"},
 	)
 	
+	// Payload sizes from 100B to 10MB
+	sizeBuckets := prometheus.ExponentialBuckets(100, 10, 6)
+	
+	httpRequestSize := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "HTTP request body size in bytes, when the Content-Length is known",
+			Buckets: sizeBuckets,
+		},
+		[]string{"route"},
+	)
+	
+	httpResponseSize := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body size in bytes",
+			Buckets: sizeBuckets,
+		},
+		[]string{"route"},
+	)
+	
+	httpRequestsByClient := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_by_client_total",
+			Help: "Total number of HTTP requests by user-agent category",
+		},
+		[]string{"client"},
+	)
+	
+	httpRoutesObserved := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_routes_observed",
+			Help: "Number of distinct route label values observed",
+		},
+	)
+	
+	jsonEncodeErrors := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_json_encode_errors_total",
+			Help: "Total number of failures encoding JSON responses",
+		},
+		[]string{"endpoint"},
+	)
+	
+	injectedErrorsTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "injected_errors_total",
+			Help: "Total number of requests failed by error injection",
+		},
+		[]string{"status_code", "route"},
+	)
+	
+	injectionObservedRate := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "error_injection_observed_rate",
+			Help: "Fraction of recent requests failed by error injection",
+		},
+	)
+	
+	idempotentReplays := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_idempotent_replays_total",
+			Help: "Total number of requests answered from a previous response with the same Idempotency-Key",
+		},
+		[]string{"route"},
+	)
+	
+	rateLimitedRequests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limited_requests_total",
+			Help: "Total number of requests rejected with 429 by the per-client rate limit",
+		},
+		[]string{"route"},
+	)
+	
+	concurrencyRejected := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "concurrency_rejected_total",
+			Help: "Total number of requests rejected with 503 because the concurrent request limit was reached",
+		},
+		[]string{"route"},
+	)
+	
+	streamConnections := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "stream_connections_active",
+			Help: "Number of streaming (SSE) connections currently open",
+		},
+	)
+	
+	panicsRecovered := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "panics_recovered_total",
+			Help: "Total number of handler panics recovered and answered with 500",
+		},
+	)
+	
+	readinessUp := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "readiness_up",
+			Help: "Whether the last readiness evaluation passed (1) or failed (0)",
+		},
+	)
+	
+	readinessCheckFailures := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "readiness_check_failures_total",
+			Help: "Total number of failed readiness checks by component",
+		},
+		[]string{"component"},
+	)
+	
+	readinessOutage := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "readiness_outage_seconds",
+			Help:    "Time from readiness starting to fail until it recovered, in seconds",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+	)
+	
+	buildInfo := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "app_build_info",
+			Help: "Build information for the running binary, always 1",
+		},
+		[]string{"go_version", "path", "version", "commit"},
+	)
+	
+	dynamicChecksCount := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dynamic_checks_count",
+			Help: "Number of readiness checks currently registered",
+		},
+	)
+	
 	// Create work metrics (for future tasks)
 	workJobsInflight := prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -57,6 +258,14 @@ func NewRegistry() *Registry {
 		},
 	)
 	
+	workJobsSnapshot := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "work_jobs_inflight_snapshot",
+			Help:    "Number of work jobs already in progress when a work request starts",
+			Buckets: []float64{0, 1, 2, 5, 10, 20, 50, 100},
+		},
+	)
+	
 	workFailuresTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "work_failures_total",
@@ -65,20 +274,99 @@ func NewRegistry() *Registry {
 		[]string{"operation"},
 	)
 	
+	workJitterApplied := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "work_jitter_applied_seconds",
+			Help:    "Random jitter added to simulated work in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+	
+	workDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "work_duration_seconds",
+			Help: "Actual duration of completed simulated work in seconds",
+			// Work requests range from a few milliseconds to tens of seconds
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"mode"},
+	)
+	
+	workCompletedTotal := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "work_completed_total",
+			Help: "Total number of work requests that ran to completion",
+		},
+	)
+	
+	workCancelledTotal := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "work_cancelled_total",
+			Help: "Total number of work requests cancelled before completion",
+		},
+	)
+	
 	// Register HTTP metrics
 	registry.MustRegister(httpRequestsTotal)
 	registry.MustRegister(httpRequestDuration)
+	registry.MustRegister(httpHandlerDuration)
+	registry.MustRegister(httpRequestSize)
+	registry.MustRegister(httpResponseSize)
+	registry.MustRegister(httpRequestsByClient)
+	registry.MustRegister(httpRoutesObserved)
+	registry.MustRegister(jsonEncodeErrors)
+	registry.MustRegister(injectedErrorsTotal)
+	registry.MustRegister(injectionObservedRate)
+	registry.MustRegister(idempotentReplays)
+	registry.MustRegister(rateLimitedRequests)
+	registry.MustRegister(concurrencyRejected)
+	registry.MustRegister(streamConnections)
+	registry.MustRegister(panicsRecovered)
+	registry.MustRegister(readinessUp)
+	registry.MustRegister(readinessCheckFailures)
+	registry.MustRegister(readinessOutage)
+	registry.MustRegister(buildInfo)
+	registry.MustRegister(dynamicChecksCount)
 	
 	// Register work metrics
 	registry.MustRegister(workJobsInflight)
+	registry.MustRegister(workJobsSnapshot)
 	registry.MustRegister(workFailuresTotal)
+	registry.MustRegister(workJitterApplied)
+	registry.MustRegister(workDuration)
+	registry.MustRegister(workCompletedTotal)
+	registry.MustRegister(workCancelledTotal)
 	
 	return &Registry{
 		registry:            registry,
 		httpRequestsTotal:   httpRequestsTotal,
 		httpRequestDuration: httpRequestDuration,
+		httpHandlerDuration: httpHandlerDuration,
+		httpRequestSize:     httpRequestSize,
+		httpResponseSize:    httpResponseSize,
+		httpRequestsByClient: httpRequestsByClient,
+		httpRoutesObserved:  httpRoutesObserved,
+		routesObserved:      make(map[string]struct{}),
+		jsonEncodeErrors:    jsonEncodeErrors,
+		injectedErrorsTotal: injectedErrorsTotal,
+		injectionRate:       injectionObservedRate,
+		idempotentReplays:   idempotentReplays,
+		rateLimitedRequests: rateLimitedRequests,
+		concurrencyRejected: concurrencyRejected,
+		streamConnections:   streamConnections,
+		panicsRecovered:     panicsRecovered,
+		readinessUp:            readinessUp,
+		readinessCheckFailures: readinessCheckFailures,
+		readinessOutage:        readinessOutage,
+		buildInfo:           buildInfo,
+		dynamicChecksCount:  dynamicChecksCount,
 		workJobsInflight:    workJobsInflight,
+		workJobsSnapshot:    workJobsSnapshot,
 		workFailuresTotal:   workFailuresTotal,
+		workJitterApplied:   workJitterApplied,
+		workDuration:        workDuration,
+		workCompletedTotal:  workCompletedTotal,
+		workCancelledTotal:  workCancelledTotal,
 	}
 }
 
@@ -89,7 +377,15 @@ func (r *Registry) GetRegistry() *prometheus.Registry {
 
 // GetHandler returns the Prometheus HTTP handler
 func (r *Registry) GetHandler() http.Handler {
-	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+	return r.GetHandlerWithTimeout(0)
+}
+
+// GetHandlerWithTimeout returns the Prometheus HTTP handler, responding 503
+// to scrapes that take longer than timeout. A zero timeout means no limit.
+func (r *Registry) GetHandlerWithTimeout(timeout time.Duration) http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{
+		Timeout: timeout,
+	})
 }
 
 // RecordHTTPRequest records metrics for an HTTP request
@@ -98,6 +394,119 @@ func (r *Registry) RecordHTTPRequest(method, route string, statusCode int, durat
 	
 	r.httpRequestsTotal.WithLabelValues(method, route, status).Inc()
 	r.httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+	r.observeRoute(route)
+}
+
+// RecordHTTPHandlerDuration records how long a request took excluding any
+// injected latency, so real handler performance can be told apart from chaos
+func (r *Registry) RecordHTTPHandlerDuration(method, route string, duration time.Duration) {
+	r.httpHandlerDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// RecordHTTPSizes records the request and response body sizes for route.
+// A negative reqBytes means the request size is unknown and is not recorded.
+func (r *Registry) RecordHTTPSizes(route string, reqBytes, respBytes int) {
+	if reqBytes >= 0 {
+		r.httpRequestSize.WithLabelValues(route).Observe(float64(reqBytes))
+	}
+	r.httpResponseSize.WithLabelValues(route).Observe(float64(respBytes))
+}
+
+// observeRoute tracks distinct route labels and updates the routes gauge
+func (r *Registry) observeRoute(route string) {
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+	
+	if _, seen := r.routesObserved[route]; seen {
+		return
+	}
+	r.routesObserved[route] = struct{}{}
+	r.httpRoutesObserved.Set(float64(len(r.routesObserved)))
+}
+
+// RecordHTTPClient counts a request for the given user-agent category
+func (r *Registry) RecordHTTPClient(client string) {
+	r.httpRequestsByClient.WithLabelValues(client).Inc()
+}
+
+// IncJSONEncodeError counts a failure to encode a JSON response
+func (r *Registry) IncJSONEncodeError(endpoint string) {
+	r.jsonEncodeErrors.WithLabelValues(endpoint).Inc()
+}
+
+// IncInjectedError counts a request failed by error injection
+func (r *Registry) IncInjectedError(route string, statusCode int) {
+	r.injectedErrorsTotal.WithLabelValues(strconv.Itoa(statusCode), route).Inc()
+}
+
+// SetErrorInjectionObservedRate records the injection rate seen over recent requests
+func (r *Registry) SetErrorInjectionObservedRate(rate float64) {
+	r.injectionRate.Set(rate)
+}
+
+// IncIdempotentReplay counts a retried request answered from the recorded response
+func (r *Registry) IncIdempotentReplay(route string) {
+	r.idempotentReplays.WithLabelValues(route).Inc()
+}
+
+// IncRateLimited counts a request rejected by the per-client rate limit
+func (r *Registry) IncRateLimited(route string) {
+	r.rateLimitedRequests.WithLabelValues(route).Inc()
+}
+
+// IncConcurrencyRejected counts a request shed by the concurrent request limit
+func (r *Registry) IncConcurrencyRejected(route string) {
+	r.concurrencyRejected.WithLabelValues(route).Inc()
+}
+
+// IncStreamConnections increments the open streaming connections gauge
+func (r *Registry) IncStreamConnections() {
+	r.streamConnections.Inc()
+}
+
+// DecStreamConnections decrements the open streaming connections gauge
+func (r *Registry) DecStreamConnections() {
+	r.streamConnections.Dec()
+}
+
+// IncPanic counts a panic recovered by the panic recovery middleware
+func (r *Registry) IncPanic() {
+	r.panicsRecovered.Inc()
+}
+
+// SetReadinessUp records whether the last readiness evaluation passed
+func (r *Registry) SetReadinessUp(up bool) {
+	if up {
+		r.readinessUp.Set(1)
+	} else {
+		r.readinessUp.Set(0)
+	}
+}
+
+// IncReadinessCheckFailure counts a failed readiness check for component
+func (r *Registry) IncReadinessCheckFailure(component string) {
+	r.readinessCheckFailures.WithLabelValues(component).Inc()
+}
+
+// ObserveReadinessOutage records how long readiness failed before recovering
+func (r *Registry) ObserveReadinessOutage(outage time.Duration) {
+	r.readinessOutage.Observe(outage.Seconds())
+}
+
+// SetBuildInfo records the Go version, main module path, version and commit of the binary
+func (r *Registry) SetBuildInfo(goVersion, path, version, commit string) {
+	r.buildInfo.WithLabelValues(goVersion, path, version, commit).Set(1)
+}
+
+// SetDynamicChecksCount records the number of registered readiness checks
+func (r *Registry) SetDynamicChecksCount(count int) {
+	r.dynamicChecksCount.Set(float64(count))
+}
+
+// ObserveWorkJobsInflightSnapshot records how many work jobs are in progress;
+// call it as a job starts, before IncWorkJobsInflight
+func (r *Registry) ObserveWorkJobsInflightSnapshot() {
+	r.workJobsSnapshot.Observe(r.GetInflightJobs())
 }
 
 // IncWorkJobsInflight increments the work jobs inflight gauge
@@ -115,6 +524,26 @@ func (r *Registry) IncWorkFailures(operation string) {
 	r.workFailuresTotal.WithLabelValues(operation).Inc()
 }
 
+// IncWorkCompleted counts a work request that ran to completion
+func (r *Registry) IncWorkCompleted() {
+	r.workCompletedTotal.Inc()
+}
+
+// IncWorkCancelled counts a work request cancelled before completion
+func (r *Registry) IncWorkCancelled() {
+	r.workCancelledTotal.Inc()
+}
+
+// ObserveWorkDuration records how long a completed work request took, by mode (sleep or cpu)
+func (r *Registry) ObserveWorkDuration(mode string, duration time.Duration) {
+	r.workDuration.WithLabelValues(mode).Observe(duration.Seconds())
+}
+
+// ObserveWorkJitter records the jitter sampled for a single work request
+func (r *Registry) ObserveWorkJitter(jitter time.Duration) {
+	r.workJitterApplied.Observe(jitter.Seconds())
+}
+
 // GetInflightJobs returns the current number of inflight jobs
 func (r *Registry) GetInflightJobs() float64 {
 	metric := &dto.Metric{}
@@ -144,4 +573,4 @@ func (r *Registry) Flush() error {
 	_ = totalMetrics
 	
 	return nil
-}
\ No newline at end of file
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestNewRegistry(t *testing.T) {
@@ -54,6 +56,34 @@ func TestRecordHTTPRequest(t *testing.T) {
 	}
 }
 
+func TestNewRegistry_HTTPDurationBuckets(t *testing.T) {
+	registry := NewRegistry(WithHTTPDurationBuckets([]float64{0.5, 2, 10}))
+	
+	registry.RecordHTTPRequest("GET", "/api/v1/work", 200, 3*time.Second)
+	
+	handler := registry.GetHandler()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	body := w.Body.String()
+	for _, expected := range []string{
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/work",le="0.5"} 0`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/work",le="2"} 0`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/work",le="10"} 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in metrics output", expected)
+		}
+	}
+	
+	// Default buckets are replaced, not extended
+	if strings.Contains(body, `http_request_duration_seconds_bucket{method="GET",route="/api/v1/work",le="0.005"}`) {
+		t.Error("Expected the default buckets to be replaced")
+	}
+}
+
 func TestWorkMetrics(t *testing.T) {
 	registry := NewRegistry()
 	
@@ -94,6 +124,90 @@ func TestWorkMetrics(t *testing.T) {
 	}
 }
 
+func TestObserveWorkDuration(t *testing.T) {
+	registry := NewRegistry()
+	
+	registry.ObserveWorkDuration("sleep", 80*time.Millisecond)
+	registry.ObserveWorkDuration("sleep", 1500*time.Millisecond)
+	registry.ObserveWorkDuration("cpu", 300*time.Millisecond)
+	
+	handler := registry.GetHandler()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	body := w.Body.String()
+	for _, expected := range []string{
+		`work_duration_seconds_bucket{mode="sleep",le="0.1"} 1`,
+		`work_duration_seconds_bucket{mode="sleep",le="2.5"} 2`,
+		`work_duration_seconds_count{mode="sleep"} 2`,
+		`work_duration_seconds_bucket{mode="cpu",le="0.25"} 0`,
+		`work_duration_seconds_bucket{mode="cpu",le="0.5"} 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in metrics output", expected)
+		}
+	}
+}
+
+func TestSetBuildInfo(t *testing.T) {
+	registry := NewRegistry()
+	
+	registry.SetBuildInfo("go1.21.0", "monitoring-dashboard-automation", "v1.4.0", "3fc1215")
+	
+	handler := registry.GetHandler()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	
+	handler.ServeHTTP(w, req)
+	
+	expected := `app_build_info{commit="3fc1215",go_version="go1.21.0",path="monitoring-dashboard-automation",version="v1.4.0"} 1`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("Expected %s in metrics output", expected)
+	}
+}
+
+// slowCollector blocks collection until released, simulating a stuck collector
+type slowCollector struct {
+	desc    *prometheus.Desc
+	release chan struct{}
+}
+
+func (c *slowCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *slowCollector) Collect(ch chan<- prometheus.Metric) {
+	<-c.release
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1)
+}
+
+func TestGetHandlerWithTimeout(t *testing.T) {
+	registry := NewRegistry()
+	
+	collector := &slowCollector{
+		desc:    prometheus.NewDesc("slow_metric", "A metric that takes long to collect", nil, nil),
+		release: make(chan struct{}),
+	}
+	defer close(collector.release)
+	registry.GetRegistry().MustRegister(collector)
+	
+	handler := registry.GetHandlerWithTimeout(50 * time.Millisecond)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	
+	start := time.Now()
+	handler.ServeHTTP(w, req)
+	
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected scrape to give up after the timeout, took %v", elapsed)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
 func TestGoMetrics(t *testing.T) {
 	registry := NewRegistry()
 	
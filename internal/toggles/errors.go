@@ -8,9 +8,10 @@ import (
 // ErrorToggle represents the configuration for error injection
 type ErrorToggle struct {
 	mu         sync.RWMutex
-	Enabled    bool    `json:"enabled"`
-	Rate       float64 `json:"rate"`        // 0.0 to 1.0
-	StatusCode int     `json:"status_code"` // HTTP status code to return
+	Enabled    bool     `json:"enabled"`
+	Rate       float64  `json:"rate"`             // 0.0 to 1.0
+	StatusCode int      `json:"status_code"`      // HTTP status code to return
+	Routes     []string `json:"routes,omitempty"` // Route patterns to inject into, all when empty
 }
 
 // NewErrorToggle creates a new ErrorToggle with default values
@@ -22,14 +23,24 @@ func NewErrorToggle() *ErrorToggle {
 	}
 }
 
-// SetConfig updates the error toggle configuration
-func (et *ErrorToggle) SetConfig(enabled bool, rate float64, statusCode int) {
+// SetConfig updates the error toggle configuration. When routes are given,
+// errors are only injected into requests matching one of those route patterns.
+func (et *ErrorToggle) SetConfig(enabled bool, rate float64, statusCode int, routes ...string) {
 	et.mu.Lock()
 	defer et.mu.Unlock()
 	
 	et.Enabled = enabled
 	et.Rate = rate
 	et.StatusCode = statusCode
+	et.Routes = append([]string(nil), routes...)
+}
+
+// Disable stops error injection, keeping the rest of the configuration
+func (et *ErrorToggle) Disable() {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	
+	et.Enabled = false
 }
 
 // GetConfig returns the current error toggle configuration
@@ -40,12 +51,21 @@ func (et *ErrorToggle) GetConfig() (bool, float64, int) {
 	return et.Enabled, et.Rate, et.StatusCode
 }
 
-// ShouldInjectError determines if an error should be injected based on the current configuration
-func (et *ErrorToggle) ShouldInjectError() (bool, int) {
+// GetRoutes returns the route patterns errors are scoped to, empty meaning all routes
+func (et *ErrorToggle) GetRoutes() []string {
 	et.mu.RLock()
 	defer et.mu.RUnlock()
 	
-	if !et.Enabled {
+	return append([]string(nil), et.Routes...)
+}
+
+// ShouldInjectError determines if an error should be injected into a request
+// for the given route pattern based on the current configuration
+func (et *ErrorToggle) ShouldInjectError(route string) (bool, int) {
+	et.mu.RLock()
+	defer et.mu.RUnlock()
+	
+	if !et.Enabled || !et.matchesRoute(route) {
 		return false, 0
 	}
 	
@@ -55,4 +75,18 @@ func (et *ErrorToggle) ShouldInjectError() (bool, int) {
 	}
 	
 	return false, 0
+}
+
+// matchesRoute reports whether route is in scope; callers must hold et.mu
+func (et *ErrorToggle) matchesRoute(route string) bool {
+	if len(et.Routes) == 0 {
+		return true
+	}
+	
+	for _, scoped := range et.Routes {
+		if scoped == route {
+			return true
+		}
+	}
+	return false
 }
\ No newline at end of file
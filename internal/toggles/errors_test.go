@@ -41,12 +41,30 @@ func TestErrorToggle_SetConfig(t *testing.T) {
 	}
 }
 
+func TestErrorToggle_Disable(t *testing.T) {
+	toggle := NewErrorToggle()
+	toggle.SetConfig(true, 1.0, 503)
+	
+	toggle.Disable()
+	
+	enabled, rate, statusCode := toggle.GetConfig()
+	if enabled {
+		t.Error("Expected the toggle to be disabled")
+	}
+	if rate != 1.0 || statusCode != 503 {
+		t.Errorf("Expected the configuration to be kept, got rate %v status %d", rate, statusCode)
+	}
+	if inject, _ := toggle.ShouldInjectError("/api/v1/ping"); inject {
+		t.Error("Expected no errors injected once disabled")
+	}
+}
+
 func TestErrorToggle_ShouldInjectError_Disabled(t *testing.T) {
 	toggle := NewErrorToggle()
 	
 	// When disabled, should never inject errors
 	for i := 0; i < 100; i++ {
-		shouldInject, statusCode := toggle.ShouldInjectError()
+		shouldInject, statusCode := toggle.ShouldInjectError("/api/v1/work")
 		if shouldInject {
 			t.Errorf("Expected no error injection when disabled, but got shouldInject=true")
 		}
@@ -62,7 +80,7 @@ func TestErrorToggle_ShouldInjectError_EnabledZeroRate(t *testing.T) {
 	
 	// With rate 0.0, should never inject errors
 	for i := 0; i < 100; i++ {
-		shouldInject, statusCode := toggle.ShouldInjectError()
+		shouldInject, statusCode := toggle.ShouldInjectError("/api/v1/work")
 		if shouldInject {
 			t.Errorf("Expected no error injection with rate 0.0, but got shouldInject=true")
 		}
@@ -78,7 +96,7 @@ func TestErrorToggle_ShouldInjectError_EnabledFullRate(t *testing.T) {
 	
 	// With rate 1.0, should always inject errors
 	for i := 0; i < 100; i++ {
-		shouldInject, statusCode := toggle.ShouldInjectError()
+		shouldInject, statusCode := toggle.ShouldInjectError("/api/v1/work")
 		if !shouldInject {
 			t.Errorf("Expected error injection with rate 1.0, but got shouldInject=false")
 		}
@@ -98,7 +116,7 @@ func TestErrorToggle_ShouldInjectError_EnabledPartialRate(t *testing.T) {
 	totalCount := 1000
 	
 	for i := 0; i < totalCount; i++ {
-		shouldInject, statusCode := toggle.ShouldInjectError()
+		shouldInject, statusCode := toggle.ShouldInjectError("/api/v1/work")
 		if shouldInject {
 			injectedCount++
 			if statusCode != 503 {
@@ -135,7 +153,7 @@ func TestErrorToggle_ConcurrentAccess(t *testing.T) {
 	// Goroutine 2: continuously check if should inject error
 	go func() {
 		for i := 0; i < 100; i++ {
-			toggle.ShouldInjectError()
+			toggle.ShouldInjectError("/api/v1/work")
 		}
 		done <- true
 	}()
@@ -145,4 +163,28 @@ func TestErrorToggle_ConcurrentAccess(t *testing.T) {
 	<-done
 	
 	// If we get here without panicking, the concurrent access test passed
-}
\ No newline at end of file
+}
+
+func TestErrorToggle_ShouldInjectError_ScopedRoutes(t *testing.T) {
+	toggle := NewErrorToggle()
+	toggle.SetConfig(true, 1.0, 503, "/api/v1/work")
+	
+	if routes := toggle.GetRoutes(); len(routes) != 1 || routes[0] != "/api/v1/work" {
+		t.Errorf("Expected routes [/api/v1/work], got %v", routes)
+	}
+	
+	if shouldInject, statusCode := toggle.ShouldInjectError("/api/v1/work"); !shouldInject || statusCode != 503 {
+		t.Errorf("Expected injection on scoped route, got %v, %d", shouldInject, statusCode)
+	}
+	
+	if shouldInject, _ := toggle.ShouldInjectError("/api/v1/ping"); shouldInject {
+		t.Error("Expected no injection on a route outside the scope")
+	}
+	
+	// Clearing the routes makes injection global again
+	toggle.SetConfig(true, 1.0, 503)
+	
+	if shouldInject, _ := toggle.ShouldInjectError("/api/v1/ping"); !shouldInject {
+		t.Error("Expected injection on every route when no routes are configured")
+	}
+}
@@ -0,0 +1,162 @@
+package toggles
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LatencyToggle represents the configuration for latency injection
+type LatencyToggle struct {
+	mu      sync.RWMutex
+	Enabled bool `json:"enabled"`
+	MinMs   int  `json:"min_ms"` // Lower bound of the injected delay
+	MaxMs   int  `json:"max_ms"` // Upper bound of the injected delay
+	
+	// Cancels the running latency ramp, if any, and the config to restore after it
+	rampCancel   context.CancelFunc
+	rampBaseline latencyConfig
+}
+
+// latencyConfig is a snapshot of the latency toggle configuration
+type latencyConfig struct {
+	enabled      bool
+	minMs, maxMs int
+}
+
+// NewLatencyToggle creates a new LatencyToggle with default values
+func NewLatencyToggle() *LatencyToggle {
+	return &LatencyToggle{
+		Enabled: false,
+		MinMs:   0,
+		MaxMs:   0,
+	}
+}
+
+// SetConfig updates the latency toggle configuration, stopping any running ramp
+func (lt *LatencyToggle) SetConfig(enabled bool, minMs, maxMs int) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	
+	lt.stopRamp()
+	lt.Enabled = enabled
+	lt.MinMs = minMs
+	lt.MaxMs = maxMs
+}
+
+// Disable stops latency injection, including a running ramp, keeping the
+// configured range
+func (lt *LatencyToggle) Disable() {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	
+	lt.stopRamp()
+	lt.Enabled = false
+}
+
+// GetConfig returns the current latency toggle configuration
+func (lt *LatencyToggle) GetConfig() (bool, int, int) {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	
+	return lt.Enabled, lt.MinMs, lt.MaxMs
+}
+
+// ShouldDelay returns a random delay within the configured range, or zero when disabled
+func (lt *LatencyToggle) ShouldDelay() time.Duration {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	
+	if !lt.Enabled || lt.MaxMs <= 0 {
+		return 0
+	}
+	
+	delayMs := lt.MinMs
+	if lt.MaxMs > lt.MinMs {
+		delayMs += rand.Intn(lt.MaxMs - lt.MinMs + 1)
+	}
+	
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+// StartRamp linearly increases the injected latency from startMs to endMs over
+// duration, updating it every interval, then restores the configuration that
+// was active before the ramp. Starting a new ramp replaces a running one.
+func (lt *LatencyToggle) StartRamp(startMs, endMs int, duration, interval time.Duration) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	
+	baseline := latencyConfig{enabled: lt.Enabled, minMs: lt.MinMs, maxMs: lt.MaxMs}
+	if lt.rampCancel != nil {
+		// Keep restoring to the configuration from before the first ramp
+		baseline = lt.rampBaseline
+	}
+	lt.stopRamp()
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	lt.rampCancel = cancel
+	lt.rampBaseline = baseline
+	lt.setDelay(startMs)
+	
+	go lt.runRamp(ctx, startMs, endMs, duration, interval)
+}
+
+// IsRamping returns whether a latency ramp is running
+func (lt *LatencyToggle) IsRamping() bool {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	
+	return lt.rampCancel != nil
+}
+
+// runRamp updates the delay on each tick until the ramp ends or is stopped
+func (lt *LatencyToggle) runRamp(ctx context.Context, startMs, endMs int, duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		
+		lt.mu.Lock()
+		if ctx.Err() != nil {
+			// Stopped while waiting for the lock
+			lt.mu.Unlock()
+			return
+		}
+		
+		elapsed := time.Since(start)
+		if elapsed >= duration {
+			lt.Enabled = lt.rampBaseline.enabled
+			lt.MinMs = lt.rampBaseline.minMs
+			lt.MaxMs = lt.rampBaseline.maxMs
+			lt.stopRamp()
+			lt.mu.Unlock()
+			return
+		}
+		
+		progress := float64(elapsed) / float64(duration)
+		lt.setDelay(startMs + int(progress*float64(endMs-startMs)))
+		lt.mu.Unlock()
+	}
+}
+
+// setDelay injects a fixed delay; callers must hold lt.mu
+func (lt *LatencyToggle) setDelay(ms int) {
+	lt.Enabled = true
+	lt.MinMs = ms
+	lt.MaxMs = ms
+}
+
+// stopRamp cancels the running ramp, if any; callers must hold lt.mu
+func (lt *LatencyToggle) stopRamp() {
+	if lt.rampCancel != nil {
+		lt.rampCancel()
+		lt.rampCancel = nil
+	}
+}
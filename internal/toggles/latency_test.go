@@ -0,0 +1,142 @@
+package toggles
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLatencyToggle(t *testing.T) {
+	toggle := NewLatencyToggle()
+	
+	if toggle == nil {
+		t.Fatal("NewLatencyToggle() returned nil")
+	}
+	
+	enabled, minMs, maxMs := toggle.GetConfig()
+	if enabled {
+		t.Errorf("Expected enabled to be false, got %v", enabled)
+	}
+	if minMs != 0 || maxMs != 0 {
+		t.Errorf("Expected zero range, got %d-%d", minMs, maxMs)
+	}
+}
+
+func TestLatencyToggle_SetConfig(t *testing.T) {
+	toggle := NewLatencyToggle()
+	
+	toggle.SetConfig(true, 100, 250)
+	
+	enabled, minMs, maxMs := toggle.GetConfig()
+	if !enabled {
+		t.Errorf("Expected enabled to be true, got %v", enabled)
+	}
+	if minMs != 100 {
+		t.Errorf("Expected minMs to be 100, got %v", minMs)
+	}
+	if maxMs != 250 {
+		t.Errorf("Expected maxMs to be 250, got %v", maxMs)
+	}
+}
+
+func TestLatencyToggle_ShouldDelay_Disabled(t *testing.T) {
+	toggle := NewLatencyToggle()
+	toggle.SetConfig(false, 100, 200)
+	
+	// When disabled, should never delay
+	for i := 0; i < 100; i++ {
+		if delay := toggle.ShouldDelay(); delay != 0 {
+			t.Errorf("Expected no delay when disabled, got %v", delay)
+		}
+	}
+}
+
+func TestLatencyToggle_ShouldDelay_Range(t *testing.T) {
+	toggle := NewLatencyToggle()
+	toggle.SetConfig(true, 10, 20)
+	
+	// Delays must stay within the configured range
+	for i := 0; i < 1000; i++ {
+		delay := toggle.ShouldDelay()
+		if delay < 10*time.Millisecond || delay > 20*time.Millisecond {
+			t.Errorf("Expected delay between 10ms and 20ms, got %v", delay)
+		}
+	}
+}
+
+func TestLatencyToggle_ShouldDelay_FixedDelay(t *testing.T) {
+	toggle := NewLatencyToggle()
+	toggle.SetConfig(true, 50, 50)
+	
+	if delay := toggle.ShouldDelay(); delay != 50*time.Millisecond {
+		t.Errorf("Expected fixed delay of 50ms, got %v", delay)
+	}
+}
+
+func TestLatencyToggle_StartRamp(t *testing.T) {
+	toggle := NewLatencyToggle()
+	toggle.SetConfig(true, 5, 10)
+	
+	toggle.StartRamp(0, 1000, 200*time.Millisecond, 10*time.Millisecond)
+	
+	if !toggle.IsRamping() {
+		t.Fatal("Expected a ramp to be running")
+	}
+	
+	// The injected latency should increase over the window
+	last := time.Duration(-1)
+	increases := 0
+	for i := 0; i < 3; i++ {
+		time.Sleep(40 * time.Millisecond)
+		delay := toggle.ShouldDelay()
+		if delay > last {
+			increases++
+		}
+		last = delay
+	}
+	if increases != 3 {
+		t.Errorf("Expected the delay to increase on every sample, last delay %v", last)
+	}
+	
+	// After the ramp the previous configuration is restored
+	time.Sleep(200 * time.Millisecond)
+	if toggle.IsRamping() {
+		t.Error("Expected the ramp to have finished")
+	}
+	enabled, minMs, maxMs := toggle.GetConfig()
+	if !enabled || minMs != 5 || maxMs != 10 {
+		t.Errorf("Expected baseline config true 5-10, got %v %d-%d", enabled, minMs, maxMs)
+	}
+}
+
+func TestLatencyToggle_SetConfigStopsRamp(t *testing.T) {
+	toggle := NewLatencyToggle()
+	
+	toggle.StartRamp(0, 1000, time.Minute, 10*time.Millisecond)
+	toggle.SetConfig(true, 20, 20)
+	
+	if toggle.IsRamping() {
+		t.Error("Expected SetConfig to stop the ramp")
+	}
+	
+	time.Sleep(30 * time.Millisecond)
+	if delay := toggle.ShouldDelay(); delay != 20*time.Millisecond {
+		t.Errorf("Expected fixed delay of 20ms, got %v", delay)
+	}
+}
+
+func TestLatencyToggle_DisableStopsRamp(t *testing.T) {
+	toggle := NewLatencyToggle()
+	
+	toggle.StartRamp(0, 1000, 50*time.Millisecond, 10*time.Millisecond)
+	toggle.Disable()
+	
+	if toggle.IsRamping() {
+		t.Error("Expected Disable to stop the ramp")
+	}
+	
+	// The ramp would otherwise restore its baseline when it ends
+	time.Sleep(80 * time.Millisecond)
+	if delay := toggle.ShouldDelay(); delay != 0 {
+		t.Errorf("Expected no delay once disabled, got %v", delay)
+	}
+}
@@ -0,0 +1,62 @@
+package toggles
+
+import (
+	"os"
+	"sync"
+)
+
+// MemoryToggle holds an allocation of the configured size to simulate memory pressure
+type MemoryToggle struct {
+	mu        sync.Mutex
+	Enabled   bool `json:"enabled"`
+	Megabytes int  `json:"megabytes"` // Size of the retained allocation
+
+	// Retained allocation, nil when disabled
+	buf []byte
+}
+
+// NewMemoryToggle creates a new MemoryToggle with default values
+func NewMemoryToggle() *MemoryToggle {
+	return &MemoryToggle{
+		Enabled:   false,
+		Megabytes: 0,
+	}
+}
+
+// SetConfig updates the memory toggle configuration, allocating a buffer of
+// megabytes MiB when enabled and releasing it when disabled
+func (mt *MemoryToggle) SetConfig(enabled bool, megabytes int) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	
+	mt.Enabled = enabled
+	mt.Megabytes = megabytes
+	
+	// Drop the previous allocation first so resizing doesn't briefly hold both
+	mt.buf = nil
+	if enabled && megabytes > 0 {
+		mt.buf = make([]byte, megabytes<<20)
+		
+		// Write to every page so the buffer is resident rather than just reserved
+		pageSize := os.Getpagesize()
+		for i := 0; i < len(mt.buf); i += pageSize {
+			mt.buf[i] = 1
+		}
+	}
+}
+
+// GetConfig returns the current memory toggle configuration
+func (mt *MemoryToggle) GetConfig() (bool, int) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	
+	return mt.Enabled, mt.Megabytes
+}
+
+// AllocatedBytes returns the size of the retained allocation
+func (mt *MemoryToggle) AllocatedBytes() int {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	
+	return len(mt.buf)
+}
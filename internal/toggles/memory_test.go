@@ -0,0 +1,46 @@
+package toggles
+
+import (
+	"testing"
+)
+
+func TestNewMemoryToggle(t *testing.T) {
+	toggle := NewMemoryToggle()
+	
+	enabled, megabytes := toggle.GetConfig()
+	if enabled {
+		t.Errorf("Expected enabled to be false, got %v", enabled)
+	}
+	if megabytes != 0 {
+		t.Errorf("Expected megabytes to be 0, got %d", megabytes)
+	}
+	if toggle.AllocatedBytes() != 0 {
+		t.Errorf("Expected no allocation, got %d bytes", toggle.AllocatedBytes())
+	}
+}
+
+func TestMemoryToggle_SetConfig(t *testing.T) {
+	toggle := NewMemoryToggle()
+	
+	toggle.SetConfig(true, 4)
+	
+	enabled, megabytes := toggle.GetConfig()
+	if !enabled || megabytes != 4 {
+		t.Errorf("Expected enabled 4MB, got %v %dMB", enabled, megabytes)
+	}
+	if toggle.AllocatedBytes() != 4<<20 {
+		t.Errorf("Expected %d bytes allocated, got %d", 4<<20, toggle.AllocatedBytes())
+	}
+	
+	// Resizing replaces the allocation
+	toggle.SetConfig(true, 1)
+	if toggle.AllocatedBytes() != 1<<20 {
+		t.Errorf("Expected %d bytes allocated, got %d", 1<<20, toggle.AllocatedBytes())
+	}
+	
+	// Disabling releases it
+	toggle.SetConfig(false, 1)
+	if toggle.AllocatedBytes() != 0 {
+		t.Errorf("Expected allocation to be released, got %d bytes", toggle.AllocatedBytes())
+	}
+}
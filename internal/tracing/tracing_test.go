@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestNewProvider_ExportsToCollector(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	provider, err := NewProvider(context.Background(), collector.URL+"/", "test-service")
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+
+	_, span := provider.Tracer("test").Start(context.Background(), "GET /api/v1/ping")
+	span.End()
+
+	// Shutdown flushes the batch to the collector
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Provider shutdown failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/v1/traces" {
+		t.Errorf("Expected one export to /v1/traces, got %v", paths)
+	}
+}
+
+func TestPropagator_TraceContext(t *testing.T) {
+	fields := Propagator.Fields()
+	if len(fields) != 2 || fields[0] != "traceparent" || fields[1] != "tracestate" {
+		t.Errorf("Expected the W3C trace context headers, got %v", fields)
+	}
+}